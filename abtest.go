@@ -0,0 +1,98 @@
+package libserver
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+)
+
+// abTestSessionKeyPrefix prefixes the session key holding the variant of an experiment
+const abTestSessionKeyPrefix = "libserver.ab."
+
+// ABVariant is a variant of an A/B test experiment
+type ABVariant struct {
+	// Name identifies the variant, e.g. "control" or "new-checkout"
+	Name string
+	// Weight is the relative share of users assigned to the variant, variants with a
+	// weight of 0 or less get no new users
+	Weight int
+}
+
+// abVariantContextKey is the context key of the variant of an experiment
+type abVariantContextKey string
+
+// ABTestMiddleware assigns each session a variant of the experiment and injects it into the
+// request context, where GetABVariant retrieves it. The assignment is stored in the session,
+// so users keep their variant; it is computed by hashing the session ID with the experiment
+// name, so sessions are spread over the variants in proportion to their weights. Sessions
+// assigned a variant that was removed are assigned again. Requests without a session are
+// passed through without variant, and requests whose variant cannot be stored in the session
+// fail with 500 Internal Server Error. It panics if no variant has a positive weight.
+func ABTestMiddleware(experimentName string, variants []ABVariant) func(http.Handler) http.Handler {
+	totalWeight := 0
+	for _, variant := range variants {
+		totalWeight += max(variant.Weight, 0)
+	}
+	if totalWeight == 0 {
+		panic("libserver: A/B test " + experimentName + " has no variant with a positive weight")
+	}
+	sessionKey := abTestSessionKeyPrefix + experimentName
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := GetSession(r.Context())
+			if session == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			variant, _ := session.Get(sessionKey).(string)
+			if !hasABVariant(variants, variant) {
+				variant = pickABVariant(variants, totalWeight, session.Id()+":"+experimentName)
+				if err := session.Set(sessionKey, variant); err != nil {
+					slog.ErrorContext(r.Context(), "libserver: cannot store the A/B test variant", "experiment", experimentName, "error", err)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+			}
+			ctx := context.WithValue(r.Context(), abVariantContextKey(experimentName), variant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// hasABVariant returns true if name is a variant with a positive weight
+func hasABVariant(variants []ABVariant, name string) bool {
+	for _, variant := range variants {
+		if variant.Name == name && variant.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pickABVariant returns the variant the key hashes to, in proportion to the weights
+func pickABVariant(variants []ABVariant, totalWeight int, key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	point := int(h.Sum64() % uint64(totalWeight))
+	for _, variant := range variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+		if point < variant.Weight {
+			return variant.Name
+		}
+		point -= variant.Weight
+	}
+	return ""
+}
+
+// GetABVariant retrieves the variant of the experiment assigned by ABTestMiddleware, or an
+// empty string if none was assigned
+func GetABVariant(ctx context.Context, experimentName string) string {
+	if variant, ok := ctx.Value(abVariantContextKey(experimentName)).(string); ok {
+		return variant
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package libserver
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// alpnHandshakeTimeout bounds the TLS handshake made to read the negotiated protocol
+const alpnHandshakeTimeout = 10 * time.Second
+
+// SetALPNProtocols appends protocols to the ALPN protocols advertised by the TLS server
+func (s *WebServer) SetALPNProtocols(protos []string) {
+	if s.server.TLSConfig == nil {
+		s.server.TLSConfig = &tls.Config{}
+	}
+	s.server.TLSConfig.NextProtos = append(s.server.TLSConfig.NextProtos, protos...)
+}
+
+// RegisterProtocolHandler registers a handler for TLS connections negotiating the given
+// ALPN protocol. Such connections are handed over to the handler instead of the HTTP
+// server, which allows multiplexing other protocols with HTTPS on the same port.
+// The protocol is advertised automatically; HTTPS must be enabled.
+func (s *WebServer) RegisterProtocolHandler(proto string, handler func(conn net.Conn)) {
+	if s.protocolHandlers == nil {
+		s.protocolHandlers = make(map[string]func(conn net.Conn))
+	}
+	s.protocolHandlers[proto] = handler
+	if s.server.TLSConfig == nil || !slices.Contains(s.server.TLSConfig.NextProtos, proto) {
+		s.SetALPNProtocols([]string{proto})
+	}
+}
+
+// serveALPN serves HTTPS on a listener dispatching connections by negotiated protocol
+func (s *WebServer) serveALPN(server *http.Server, ln net.Listener) error {
+	config := server.TLSConfig.Clone()
+	for _, proto := range []string{"h2", "http/1.1"} {
+		if !slices.Contains(config.NextProtos, proto) {
+			config.NextProtos = append(config.NextProtos, proto)
+		}
+	}
+
+	return server.Serve(newALPNListener(tls.NewListener(ln, config), s.protocolHandlers))
+}
+
+// alpnListener completes the TLS handshake of accepted connections and hands them
+// over to the protocol handler registered for the negotiated protocol. The other
+// connections are returned by Accept to the HTTP server.
+type alpnListener struct {
+	net.Listener
+	handlers  map[string]func(conn net.Conn)
+	connCh    chan net.Conn
+	errCh     chan error
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// newALPNListener wraps a TLS listener and starts accepting connections
+func newALPNListener(inner net.Listener, handlers map[string]func(conn net.Conn)) *alpnListener {
+	l := &alpnListener{
+		Listener: inner,
+		handlers: handlers,
+		connCh:   make(chan net.Conn),
+		errCh:    make(chan error, 1),
+		closeCh:  make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+// acceptLoop accepts connections and dispatches them without blocking on handshakes
+func (l *alpnListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.errCh <- err
+			return
+		}
+		go l.dispatch(conn)
+	}
+}
+
+// dispatch performs the handshake and routes the connection by negotiated protocol
+func (l *alpnListener) dispatch(conn net.Conn) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		conn.Close()
+		return
+	}
+	tlsConn.SetDeadline(time.Now().Add(alpnHandshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return
+	}
+	tlsConn.SetDeadline(time.Time{})
+
+	if handler, ok := l.handlers[tlsConn.ConnectionState().NegotiatedProtocol]; ok {
+		handler(tlsConn)
+		return
+	}
+	select {
+	case l.connCh <- tlsConn:
+	case <-l.closeCh:
+		tlsConn.Close()
+	}
+}
+
+// Accept returns the next connection to be served by the HTTP server
+func (l *alpnListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connCh:
+		return conn, nil
+	case err := <-l.errCh:
+		// Keep the error for subsequent calls
+		l.errCh <- err
+		return nil, err
+	}
+}
+
+// Close stops accepting connections
+func (l *alpnListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closeCh)
+		err = l.Listener.Close()
+	})
+	return err
+}
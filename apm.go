@@ -0,0 +1,39 @@
+package libserver
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// APMHook integrates an Application Performance Monitoring library, such as Datadog or
+// New Relic, with the request lifecycle
+type APMHook interface {
+	// BeforeRequest is called when a request arrives, before the session injection, and
+	// returns the span of the request, an opaque value managed by the APM library
+	BeforeRequest(r *http.Request) (span any)
+	// AfterRequest is called with the span once the handler returned. err is non-nil if the
+	// handler panicked, in which case the panic goes on once AfterRequest returns.
+	AfterRequest(span any, statusCode int, err error)
+}
+
+// SetAPMHook sets the APM hook called around every request, see APMHook
+func (s *WebServer) SetAPMHook(hook APMHook) {
+	s.apmHook = hook
+}
+
+// finishAPMSpan ends the span of the request, it must be deferred so that panics are reported
+func (s *WebServer) finishAPMSpan(span any, rw *responseWriter) {
+	p := recover()
+	var err error
+	statusCode := rw.statusCode
+	if p != nil {
+		err = fmt.Errorf("panic: %v", p)
+		if !rw.wroteHeader {
+			statusCode = http.StatusInternalServerError
+		}
+	}
+	s.apmHook.AfterRequest(span, statusCode, err)
+	if p != nil {
+		panic(p)
+	}
+}
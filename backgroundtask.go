@@ -0,0 +1,58 @@
+package libserver
+
+import (
+	"context"
+)
+
+// backgroundTask is a named function running for the lifetime of the web server
+type backgroundTask struct {
+	name string
+	task func(ctx context.Context)
+}
+
+// AddBackgroundTask registers a task started in its own goroutine when the server starts.
+// The task's context is cancelled when the server stops, and Stop waits for the task
+// to return for at most the drain timeout.
+func (s *WebServer) AddBackgroundTask(name string, task func(ctx context.Context)) {
+	s.tasks = append(s.tasks, backgroundTask{name: name, task: task})
+}
+
+// startBackgroundTasks starts all registered background tasks
+func (s *WebServer) startBackgroundTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancelTasks = cancel
+	for _, task := range s.tasks {
+		s.tasksWg.Add(1)
+		go s.runBackgroundTask(ctx, task)
+	}
+}
+
+// runBackgroundTask runs a background task, recovering and logging any panic
+func (s *WebServer) runBackgroundTask(ctx context.Context, task backgroundTask) {
+	defer s.tasksWg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger().Error("libserver: background task panicked", "task", task.name, "panic", r)
+		}
+	}()
+	task.task(ctx)
+}
+
+// stopBackgroundTasks cancels the background tasks and waits for them until ctx is done
+func (s *WebServer) stopBackgroundTasks(ctx context.Context) {
+	if s.cancelTasks == nil {
+		return
+	}
+	s.cancelTasks()
+
+	done := make(chan struct{})
+	go func() {
+		s.tasksWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger().Warn("libserver: background tasks did not stop within the drain timeout")
+	}
+}
@@ -0,0 +1,58 @@
+package libserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// RequestBodyKey is the context key for accessing the buffered request body
+const RequestBodyKey ContextKey = "requestBody"
+
+// BufferBody reads the whole request body, replaces r.Body with a fresh reader and
+// stores the raw bytes in the request context, so both middleware and handlers can
+// read the body independently
+func BufferBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(RequestBodyKey).([]byte); ok {
+			// Already buffered by an outer middleware
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var body []byte
+		if r.Body != nil {
+			var err error
+			body, err = io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+		}
+		if body == nil {
+			body = []byte{}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), RequestBodyKey, body))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetRequestBodyFromContext retrieves the buffered request body from a request context
+func GetRequestBodyFromContext(ctx context.Context) []byte {
+	if body, ok := ctx.Value(RequestBodyKey).([]byte); ok {
+		return body
+	}
+	return nil
+}
+
+// ResetRequestBody replaces r.Body with a fresh reader over the buffered body,
+// allowing the body to be read again after a middleware consumed it
+func ResetRequestBody(r *http.Request) {
+	if body := GetRequestBodyFromContext(r.Context()); body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+}
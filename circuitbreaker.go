@@ -0,0 +1,142 @@
+package libserver
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by CircuitBreaker.Do when the circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// errUpstreamFailure is recorded by the circuit breaker middleware for 5xx responses
+var errUpstreamFailure = errors.New("upstream failure")
+
+// errCallPanicked is recorded by the circuit breaker when the call panics
+var errCallPanicked = errors.New("call panicked")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets calls through and counts consecutive failures
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects calls until the reset timeout expires
+	CircuitOpen
+	// CircuitHalfOpen lets a single trial call through to probe the upstream
+	CircuitHalfOpen
+)
+
+// CircuitBreaker fails fast when an upstream keeps failing
+type CircuitBreaker struct {
+	failureThreshold int
+	resetTimeout     time.Duration
+	failures         int
+	state            CircuitState
+	openedAt         time.Time
+	mu               *sync.Mutex
+}
+
+// NewCircuitBreaker creates a circuit breaker opening after failureThreshold consecutive
+// errors and allowing a trial call after resetTimeout
+func NewCircuitBreaker(failureThreshold int, resetTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		resetTimeout:     resetTimeout,
+		state:            CircuitClosed,
+		mu:               &sync.Mutex{},
+	}
+}
+
+// Do calls fn unless the circuit is open, in which case ErrCircuitOpen is returned.
+// A panic of fn counts as a failure and is propagated to the caller.
+func (cb *CircuitBreaker) Do(fn func() error) error {
+	if !cb.allow() {
+		return ErrCircuitOpen
+	}
+	returned := false
+	defer func() {
+		// fn panicked: record the failure so a half-open circuit does not stay stuck
+		if !returned {
+			cb.record(errCallPanicked)
+		}
+	}()
+	err := fn()
+	returned = true
+	cb.record(err)
+	return err
+}
+
+// allow reports whether a call may go through, moving to half-open after the reset timeout
+func (cb *CircuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.resetTimeout {
+			return false
+		}
+		cb.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		// A trial call is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the circuit state with the result of a call
+func (cb *CircuitBreaker) record(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if err == nil {
+		cb.failures = 0
+		cb.state = CircuitClosed
+		return
+	}
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.failureThreshold {
+		cb.state = CircuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.resetTimeout {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// Reset closes the circuit and clears the failure count
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.state = CircuitClosed
+}
+
+// CircuitBreakerMiddleware wraps an upstream-calling handler with a circuit breaker.
+// 5xx responses and panics count as failures, and 502 Bad Gateway is returned while the circuit is open.
+func CircuitBreakerMiddleware(cb *CircuitBreaker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := cb.Do(func() error {
+				rw := newResponseWriter(w)
+				next.ServeHTTP(rw, r)
+				if rw.statusCode >= http.StatusInternalServerError {
+					return errUpstreamFailure
+				}
+				return nil
+			})
+			if errors.Is(err, ErrCircuitOpen) {
+				http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package libserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultConcurrencyQueueTimeout is the default time a request waits for a free slot when
+// the number of concurrent requests is limited
+const DefaultConcurrencyQueueTimeout = time.Second
+
+// SetMaxConcurrentRequests limits the number of handlers executing simultaneously to n.
+// Unlike a rate limiter, excess requests are queued: they wait for a free slot for at most
+// the queue timeout (see SetConcurrencyQueueTimeout), then get 503 Service Unavailable.
+// The limit applies to every route, inside the session injection. n <= 0 removes the limit.
+func (s *WebServer) SetMaxConcurrentRequests(n int) {
+	defer s.invalidateChains()
+	if n <= 0 {
+		s.semaphore = nil
+		return
+	}
+	s.semaphore = make(chan struct{}, n)
+}
+
+// SetConcurrencyQueueTimeout sets how long a request waits for a free slot when the number
+// of concurrent requests is limited, DefaultConcurrencyQueueTimeout by default. A timeout
+// of 0 rejects the requests exceeding the limit immediately.
+func (s *WebServer) SetConcurrencyQueueTimeout(d time.Duration) {
+	s.queueTimeout = d
+}
+
+// limitConcurrency wraps the handler with the concurrency limit
+func (s *WebServer) limitConcurrency(semaphore chan struct{}, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case semaphore <- struct{}{}:
+		default:
+			if !s.waitForSlot(semaphore, r) {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		defer func() { <-semaphore }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// waitForSlot waits for a free slot until the queue timeout, returning false if none was freed
+func (s *WebServer) waitForSlot(semaphore chan struct{}, r *http.Request) bool {
+	if s.queueTimeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(s.queueTimeout)
+	defer timer.Stop()
+	select {
+	case semaphore <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package libserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxCookieSize is the size of a cookie browsers are required to support (RFC 6265)
+const maxCookieSize = 4096
+
+// sessionCookieReserve is the room kept in a cookie for the session ID and the attributes
+const sessionCookieReserve = 256
+
+// SetSessionCookieSameSite sets the SameSite attribute of the session cookie, which defaults
+// to http.SameSiteLaxMode. http.SameSiteNoneMode requires HTTPS.
+func (s *WebServer) SetSessionCookieSameSite(mode http.SameSite) {
+	s.sameSite = mode
+}
+
+// sessionCookie returns the session cookie with the given name and value
+func (s *WebServer) sessionCookie(name, value string) *http.Cookie {
+	sameSite := s.sameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.withHttps,
+		SameSite: sameSite,
+	}
+}
+
+// setSessionCookie sets the session cookie on the response, unless a cookie with the same
+// name was already set, e.g. by a wrapped handler calling another wrapped handler
+func setSessionCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	for _, line := range w.Header().Values("Set-Cookie") {
+		if existing, err := http.ParseSetCookie(line); err == nil && existing.Name == cookie.Name {
+			return
+		}
+	}
+	http.SetCookie(w, cookie)
+}
+
+// isCookieNameChar returns true for the token characters allowed in a cookie name (RFC 6265)
+func isCookieNameChar(c rune) bool {
+	return c > 0x20 && c < 0x7f && !strings.ContainsRune(`()<>@,;:\"/[]?={}`, c)
+}
+
+// validateCookieConfig checks that the session cookie will be accepted by browsers
+func (s *WebServer) validateCookieConfig() error {
+	name := s.GetSessionCookieName()
+	if name == "" {
+		return errors.New("session cookie name is empty")
+	}
+	if i := strings.IndexFunc(name, func(c rune) bool { return !isCookieNameChar(c) }); i >= 0 {
+		return fmt.Errorf("session cookie name %q has the invalid character %q, use SetSessionCookieName", name, name[i])
+	}
+	if len(name)+sessionCookieReserve > maxCookieSize {
+		return fmt.Errorf("session cookie name is %d bytes long, cookies are limited to %d bytes", len(name), maxCookieSize)
+	}
+	if s.sameSite == http.SameSiteNoneMode && !s.withHttps {
+		return errors.New("session cookie with SameSite=None requires HTTPS, browsers reject it without the Secure attribute")
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package libserver
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures the CORS middleware
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests, "*" allows any origin
+	// but only for requests without credentials, see AllowCredentials
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in preflight responses (default GET, HEAD and POST)
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflight responses.
+	// When empty, the headers requested by the preflight request are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers exposed to the browser
+	ExposedHeaders []string
+	// AllowCredentials allows credentialed requests (cookies, authorization headers) from the
+	// origins listed in AllowedOrigins. "*" never allows them: other origins get the
+	// non-credentialed "*" response, which browsers refuse to expose to credentialed requests.
+	AllowCredentials bool
+	// SessionCookieName enables credentialed responses for HTTPS requests carrying this
+	// session cookie, even when AllowCredentials is false
+	SessionCookieName string
+	// MaxAge is the number of seconds preflight responses may be cached, 0 to omit the header
+	MaxAge int
+}
+
+// allowsOrigin returns true if the origin is in the allowed list
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, "*") || c.listsOrigin(origin)
+}
+
+// listsOrigin returns true if the origin is listed explicitly, as credentialed requests require
+func (c CORSConfig) listsOrigin(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, origin)
+}
+
+// isCredentialed returns true if the response to the request must allow credentials
+func (c CORSConfig) isCredentialed(r *http.Request) bool {
+	if c.AllowCredentials {
+		return true
+	}
+	if c.SessionCookieName == "" || r.TLS == nil {
+		return false
+	}
+	_, err := r.Cookie(c.SessionCookieName)
+	return err == nil
+}
+
+// CORSMiddleware adds the CORS headers to the responses of allowed origins and answers
+// preflight requests. Credentialed responses reflect the request origin instead of "*",
+// as required by browsers, and set Vary: Origin; they are only sent to the origins listed
+// in AllowedOrigins, never to the ones allowed by "*".
+func CORSMiddleware(config CORSConfig) func(http.Handler) http.Handler {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if applyCORSHeaders(config, w, r) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// applyCORSHeaders sets the CORS headers for the request and answers preflight requests.
+// It returns true if the response was written.
+func applyCORSHeaders(config CORSConfig, w http.ResponseWriter, r *http.Request) bool {
+	header := w.Header()
+	wildcard := slices.Contains(config.AllowedOrigins, "*")
+	credentialed := config.isCredentialed(r)
+	if !wildcard || credentialed {
+		header.Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !config.allowsOrigin(origin) {
+		return false
+	}
+
+	if credentialed && config.listsOrigin(origin) {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	} else if wildcard {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		if len(config.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	// Preflight request
+	header.Set("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
+	if len(config.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if config.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// isPreflight returns true if the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// SetCORSConfig attaches a CORS config to the server, which then answers preflight requests
+// of every route directly, without calling the route handler or creating a session.
+// Responses to actual requests still need CORSMiddleware, or use EnableCORS instead.
+func (s *WebServer) SetCORSConfig(config CORSConfig) {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	s.corsConfig = &config
+}
+
+// EnableCORS enables CORS on every route, including the ones registered afterwards: preflight
+// requests are answered as with SetCORSConfig (method-based routes get an OPTIONS route, see
+// AddHandler), and the CORS headers are set on the responses before the handler runs, so
+// they are kept by recovery middleware answering a panicking handler. Vary: Origin is set
+// on every response, so caches do not serve a response to another origin.
+func (s *WebServer) EnableCORS(config CORSConfig) {
+	s.SetCORSConfig(config)
+	s.corsHeaders = true
+}
+
+// applyServerCORSHeaders sets the CORS headers of the attached config on the response
+func (s *WebServer) applyServerCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	applyCORSHeaders(*s.corsConfig, w, r)
+	for _, vary := range w.Header().Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(name), "Origin") {
+				return
+			}
+		}
+	}
+	w.Header().Add("Vary", "Origin")
+}
+
+// handlePreflight answers a preflight request with the attached CORS config.
+// Origins that are not allowed get a 403 Forbidden response.
+func (s *WebServer) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	if !applyCORSHeaders(*s.corsConfig, w, r) {
+		w.Header().Set("Vary", "Origin, Access-Control-Request-Method")
+		w.WriteHeader(http.StatusForbidden)
+	}
+}
+
+// SessionCORSMiddleware returns a CORS middleware detecting credentialed requests by the
+// server's session cookie when HTTPS is enabled, see CORSConfig.SessionCookieName
+func (s *WebServer) SessionCORSMiddleware(config CORSConfig) func(http.Handler) http.Handler {
+	if s.withHttps && config.SessionCookieName == "" {
+		config.SessionCookieName = s.GetSessionCookieName()
+	}
+	return CORSMiddleware(config)
+}
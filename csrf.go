@@ -0,0 +1,90 @@
+package libserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// CSRFTokenKey is the context key for accessing the CSRF token of the request
+const CSRFTokenKey ContextKey = "csrfToken"
+
+// signCSRFToken returns the signed token value for a random nonce
+func signCSRFToken(secret []byte, nonce string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(nonce))
+	return nonce + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validCSRFToken returns true if the token was signed with the secret
+func validCSRFToken(secret []byte, token string) bool {
+	nonce, _, found := strings.Cut(token, ".")
+	if !found || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signCSRFToken(secret, nonce)), []byte(token))
+}
+
+// isSafeMethod returns true for the methods that must not change state
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// DoubleSubmitCSRFMiddleware protects against CSRF without server-side state, using the
+// double-submit cookie pattern: a random token signed with secret is issued in the cookieName
+// cookie, readable by scripts, and state-changing requests (other than GET, HEAD, OPTIONS and
+// TRACE) must send the same value in the headerName header, or get 403 Forbidden. The
+// signature ensures the cookie was issued by this server and not planted by another subdomain.
+// The token is injected into the request context, e.g. to render it in a page.
+func DoubleSubmitCSRFMiddleware(cookieName, headerName string, secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			if cookie, err := r.Cookie(cookieName); err == nil && validCSRFToken(secret, cookie.Value) {
+				token = cookie.Value
+			}
+
+			if !isSafeMethod(r.Method) {
+				header := r.Header.Get(headerName)
+				if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+					http.Error(w, "invalid CSRF token", http.StatusForbidden)
+					return
+				}
+			} else if token == "" {
+				nonce, err := randomToken()
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				token = signCSRFToken(secret, nonce)
+				http.SetCookie(w, &http.Cookie{
+					Name:     cookieName,
+					Value:    token,
+					Path:     "/",
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			ctx := context.WithValue(r.Context(), CSRFTokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetCSRFTokenFromContext retrieves the CSRF token set by DoubleSubmitCSRFMiddleware
+func GetCSRFTokenFromContext(ctx context.Context) string {
+	if token, ok := ctx.Value(CSRFTokenKey).(string); ok {
+		return token
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package libserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDeadlineHeaders are the deadline headers checked when none are given
+var DefaultDeadlineHeaders = []string{"X-Envoy-Expected-Rq-Timeout-Ms"}
+
+// DeadlineHeaderMiddleware bounds the request context by the time budget announced by a load
+// balancer, so that handlers and their downstream calls stop working on requests the client
+// will discard. The headers (DefaultDeadlineHeaders if empty) are checked in order and the
+// first one holding a positive number of milliseconds is used; requests without one are
+// served unchanged. An earlier deadline already set on the context is kept.
+func DeadlineHeaderMiddleware(headers []string) func(http.Handler) http.Handler {
+	if len(headers) == 0 {
+		headers = DefaultDeadlineHeaders
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, header := range headers {
+				ms, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get(header)), 10, 64)
+				if err != nil || ms <= 0 {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+				defer cancel()
+				r = r.WithContext(ctx)
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
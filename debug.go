@@ -0,0 +1,209 @@
+package libserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// DebugInfoPath is the path of the debug information endpoint
+const DebugInfoPath = "/__debug/info"
+
+// debugBodyLimit is the maximum number of body bytes logged in debug mode
+const debugBodyLimit = 4096
+
+// debugContextKeys are the context keys whose values are logged in debug mode
+var debugContextKeys = []ContextKey{
+	TenantIDKey, LanguageKey, DigestUsernameKey, RequestFingerprintKey, CSRFTokenKey,
+}
+
+// debugContextCapture holds the context the handler was called with, which carries the
+// values added by the middleware
+type debugContextCapture struct {
+	ctx context.Context
+}
+
+// debugContextCaptureKey is the context key of the debug context capture
+type debugContextCaptureKey struct{}
+
+// sensitiveKeyParts are the key fragments whose values are redacted in debug logs
+var sensitiveKeyParts = []string{"password", "secret", "token", "key", "auth", "credential", "cookie"}
+
+// EnableDebugMode enables verbose diagnostics for development: every request and response
+// (headers and the first bytes of the bodies) is logged with the sanitized session contents
+// and the request context values, responses carry an X-Debug: true header, and the server
+// configuration is served as JSON at /__debug/info. Never enable it in production.
+// It returns ErrCustomRouter if the server uses a custom router, see WithRouter, and the
+// errors of Register if /__debug/info conflicts with a route.
+func (s *WebServer) EnableDebugMode() error {
+	if s.debug {
+		return nil
+	}
+	if err := s.registerEndpoint(s.mux, DebugInfoPath, http.HandlerFunc(s.debugInfo)); err != nil {
+		return err
+	}
+	s.debug = true
+	s.invalidateChains()
+	s.logger().Info("libserver: debug mode enabled", "routes", s.routes, "middleware", s.Middleware())
+	return nil
+}
+
+// funcName returns the name of a function value
+func funcName(fn any) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
+
+// debugInfo serves the server configuration as JSON
+func (s *WebServer) debugInfo(w http.ResponseWriter, r *http.Request) {
+	listeners := make([]string, 0, len(s.listeners))
+	for _, listener := range s.listeners {
+		listeners = append(listeners, listener.Addr)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Debug", "true")
+	json.NewEncoder(w).Encode(map[string]any{
+		"applicationName":   s.applicationName,
+		"address":           s.address,
+		"port":              s.port,
+		"boundPort":         s.BoundPort(),
+		"https":             s.withHttps,
+		"sessionCookieName": s.GetSessionCookieName(),
+		"sessionManager":    fmt.Sprintf("%T", s.sessionManager),
+		"drainTimeout":      s.drainTimeout.String(),
+		"bodyBuffering":     s.bufferBody,
+		"routes":            s.routes,
+		"middleware":        s.Middleware(),
+		"listeners":         listeners,
+		"backgroundTasks":   len(s.tasks),
+		"stats":             s.Stats(),
+	})
+}
+
+// debugResponseWriter copies the beginning of the response body for logging
+type debugResponseWriter struct {
+	*responseWriter
+	body bytes.Buffer
+}
+
+// Write writes the data and keeps the first bytes for logging
+func (w *debugResponseWriter) Write(b []byte) (int, error) {
+	if remaining := debugBodyLimit - w.body.Len(); remaining > 0 {
+		w.body.Write(b[:min(len(b), remaining)])
+	}
+	return w.responseWriter.Write(b)
+}
+
+// debugHandler logs the request and the response of the handler
+func (s *WebServer) debugHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Read the beginning of the body and put it back in front of the rest for the handler
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(r.Body, debugBodyLimit+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		}
+
+		w.Header().Set("X-Debug", "true")
+		capture := &debugContextCapture{ctx: r.Context()}
+		r = r.WithContext(context.WithValue(r.Context(), debugContextCaptureKey{}, capture))
+		dw := &debugResponseWriter{responseWriter: newResponseWriter(w)}
+		next.ServeHTTP(dw, r)
+
+		var session map[string]any
+		if current := GetSession(capture.ctx); current != nil {
+			session = sanitizedSession(current)
+		}
+		s.logger().InfoContext(r.Context(), "libserver: debug",
+			"method", r.Method, "uri", r.URL.RequestURI(), "remoteAddr", r.RemoteAddr,
+			"requestHeaders", sanitizedHeader(r.Header), "requestBody", truncateBody(body),
+			"status", dw.statusCode, "bytes", dw.bytesWritten,
+			"responseHeaders", sanitizedHeader(w.Header()), "responseBody", truncateBody(dw.body.Bytes()),
+			"session", session, "context", debugContextValues(capture.ctx))
+	})
+}
+
+// captureDebugContext records the context the handler is called with for debugHandler
+func captureDebugContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if capture, ok := r.Context().Value(debugContextCaptureKey{}).(*debugContextCapture); ok {
+			capture.ctx = r.Context()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// debugContextValues returns the known context values set on ctx, with sensitive values redacted
+func debugContextValues(ctx context.Context) map[string]any {
+	values := make(map[string]any)
+	for _, key := range debugContextKeys {
+		value := ctx.Value(key)
+		if value == nil {
+			continue
+		}
+		if isSensitiveKey(string(key)) {
+			value = "[redacted]"
+		}
+		values[string(key)] = value
+	}
+	return values
+}
+
+// isSensitiveKey returns true if values stored under the key must not be logged
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizedHeader returns a copy of the header with sensitive values redacted
+func sanitizedHeader(header http.Header) http.Header {
+	sanitized := header.Clone()
+	for key := range sanitized {
+		if isSensitiveKey(key) {
+			sanitized[key] = []string{"[redacted]"}
+		}
+	}
+	return sanitized
+}
+
+// sanitizedSession returns the session contents with sensitive values redacted.
+// Only sessions exposing a Keys method, such as DefaultSession, can be listed.
+func sanitizedSession(session Session) map[string]any {
+	contents := map[string]any{"id": "[redacted]"}
+	lister, ok := session.(interface{ Keys() []string })
+	if !ok {
+		return contents
+	}
+	for _, key := range lister.Keys() {
+		if isSensitiveKey(key) {
+			contents[key] = "[redacted]"
+		} else {
+			contents[key] = session.Get(key)
+		}
+	}
+	return contents
+}
+
+// truncateBody formats a body for logging, truncated to the debug body limit
+func truncateBody(body []byte) string {
+	if len(body) > debugBodyLimit {
+		return fmt.Sprintf("%q... (truncated)", body[:debugBodyLimit])
+	}
+	return fmt.Sprintf("%q", body)
+}
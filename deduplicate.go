@@ -0,0 +1,104 @@
+package libserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// dedupSessionKey is the session key under which recent request hashes are stored
+const dedupSessionKey = "libserver.dedup"
+
+// dedupLock serializes the deduplication checks of a session
+type dedupLock struct {
+	mu   *sync.Mutex
+	refs int
+}
+
+// dedupLocks holds the locks of the sessions being checked, so that checks of different
+// sessions run concurrently
+var dedupLocks = struct {
+	locks map[string]*dedupLock
+	mu    *sync.Mutex
+}{locks: make(map[string]*dedupLock), mu: &sync.Mutex{}}
+
+// lockDedupSession locks the session with the given ID for a deduplication check and returns
+// the function unlocking it
+func lockDedupSession(id string) (unlock func()) {
+	dedupLocks.mu.Lock()
+	lock, ok := dedupLocks.locks[id]
+	if !ok {
+		lock = &dedupLock{mu: &sync.Mutex{}}
+		dedupLocks.locks[id] = lock
+	}
+	lock.refs++
+	dedupLocks.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		dedupLocks.mu.Lock()
+		defer dedupLocks.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(dedupLocks.locks, id)
+		}
+	}
+}
+
+// DeduplicateRequests records requestHash in the session for ttl and reports whether the
+// same hash was already recorded within its ttl, in which case the handler should not
+// repeat the request's side effects. It returns the error of the session if the hash could
+// not be recorded, in which case the request should fail, since a retry would not be
+// detected as a duplicate.
+func DeduplicateRequests(session Session, requestHash string, ttl time.Duration) (isDuplicate bool, err error) {
+	defer lockDedupSession(session.Id())()
+
+	now := time.Now()
+	seen, _ := session.Get(dedupSessionKey).(map[string]time.Time)
+	if seen == nil {
+		seen = make(map[string]time.Time)
+	}
+	// Drop the expired hashes
+	for hash, expiresAt := range seen {
+		if now.After(expiresAt) {
+			delete(seen, hash)
+		}
+	}
+
+	_, isDuplicate = seen[requestHash]
+	if !isDuplicate {
+		seen[requestHash] = now.Add(ttl)
+	}
+	if err := session.Set(dedupSessionKey, seen); err != nil {
+		return false, err
+	}
+	return isDuplicate, nil
+}
+
+// ComputeRequestHash returns a hash of the request method, path and body for use with
+// DeduplicateRequests. The body is restored so the handler can still read it.
+func ComputeRequestHash(r *http.Request) (string, error) {
+	body := GetRequestBodyFromContext(r.Context())
+	if body == nil && r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	bodyHash := sha256.Sum256(body)
+	hash := sha256.New()
+	io.WriteString(hash, r.Method)
+	hash.Write([]byte{0})
+	io.WriteString(hash, r.URL.Path)
+	hash.Write([]byte{0})
+	hash.Write(bodyHash[:])
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
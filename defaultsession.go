@@ -1,6 +1,11 @@
 package libserver
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,6 +15,34 @@ import (
 // DefaultSessionExpiration is the default session expiration duration
 const DefaultSessionExpiration = time.Hour
 
+// ErrSessionTooLarge is returned by Set when storing a value would exceed the maximum session size
+var ErrSessionTooLarge = errors.New("session size limit exceeded")
+
+// TTLStrategy defines how the expiration of a session is computed
+type TTLStrategy int
+
+const (
+	// IdleTTL expires a session after its expiration duration without any Update call
+	IdleTTL TTLStrategy = iota
+	// AbsoluteTTL expires a session after its expiration duration since creation, regardless of activity
+	AbsoluteTTL
+)
+
+// IDGenerator generates unique session IDs
+type IDGenerator func() string
+
+// UUIDGenerator is the default ID generator, returning random UUIDs
+func UUIDGenerator() string {
+	return uuid.New().String()
+}
+
+// SecureIDGenerator returns 256-bit random IDs from crypto/rand, encoded as URL-safe base64
+func SecureIDGenerator() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
 // DefaultSession is the default implementation of the Session interface
 type DefaultSession struct {
 	data               map[string]any
@@ -18,23 +51,65 @@ type DefaultSession struct {
 	mu                 *sync.RWMutex
 	id                 string
 	expirationDuration time.Duration
+	maxSize            int
+	sizes              map[string]int
+	size               int
+	ttlStrategy        TTLStrategy
+	changed            map[string]struct{}
+}
+
+// DefaultSessionOptions configures a DefaultSession created with NewDefaultSessionWithOptions
+type DefaultSessionOptions struct {
+	// Expiration is the session expiration duration, DefaultSessionExpiration if zero
+	Expiration time.Duration
+	// IDGenerator generates the session ID, UUIDGenerator if nil
+	IDGenerator IDGenerator
+	// MaxSize is the maximum total size in bytes of the JSON-serialized keys and values,
+	// 0 for no limit
+	MaxSize int
+	// TTLStrategy selects idle (default) or absolute expiration
+	TTLStrategy TTLStrategy
+}
+
+// NewDefaultSession creates a new session with default expiration (1 hour).
+// An optional ID generator replaces the default UUID generator.
+func NewDefaultSession(generator ...IDGenerator) *DefaultSession {
+	return NewDefaultSessionWithExpiration(DefaultSessionExpiration, generator...)
+}
+
+// NewDefaultSessionWithExpiration creates a new session with a custom expiration duration.
+// An optional ID generator replaces the default UUID generator.
+func NewDefaultSessionWithExpiration(expiration time.Duration, generator ...IDGenerator) *DefaultSession {
+	generate := UUIDGenerator
+	if len(generator) > 0 && generator[0] != nil {
+		generate = generator[0]
+	}
+	return newDefaultSessionWithID(generate(), expiration)
 }
 
-// NewDefaultSession creates a new session with default expiration (1 hour)
-func NewDefaultSession() *DefaultSession {
-	return NewDefaultSessionWithExpiration(DefaultSessionExpiration)
+// NewDefaultSessionWithOptions creates a new session with the given options
+func NewDefaultSessionWithOptions(options DefaultSessionOptions) *DefaultSession {
+	if options.Expiration == 0 {
+		options.Expiration = DefaultSessionExpiration
+	}
+	session := NewDefaultSessionWithExpiration(options.Expiration, options.IDGenerator)
+	session.maxSize = options.MaxSize
+	session.ttlStrategy = options.TTLStrategy
+	return session
 }
 
-// NewDefaultSessionWithExpiration creates a new session with a custom expiration duration
-func NewDefaultSessionWithExpiration(expiration time.Duration) *DefaultSession {
+// newDefaultSessionWithID creates a new session with the given ID and expiration duration
+func newDefaultSessionWithID(id string, expiration time.Duration) *DefaultSession {
 	now := time.Now()
 	return &DefaultSession{
 		data:               make(map[string]any),
 		createdAt:          now,
 		lastAccessedAt:     now,
 		mu:                 &sync.RWMutex{},
-		id:                 uuid.New().String(),
+		id:                 id,
 		expirationDuration: expiration,
+		sizes:              make(map[string]int),
+		changed:            make(map[string]struct{}),
 	}
 }
 
@@ -45,11 +120,27 @@ func (s *DefaultSession) Get(key string) any {
 	return s.data[key]
 }
 
-// Set stores a value in the session
-func (s *DefaultSession) Set(key string, value any) {
+// Set stores a value in the session. When a maximum size is configured, the value must be
+// JSON-serializable and ErrSessionTooLarge is returned, leaving the session unchanged, if
+// storing it would exceed the limit.
+func (s *DefaultSession) Set(key string, value any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.maxSize > 0 {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		entrySize := len(key) + len(encoded)
+		if s.size-s.sizes[key]+entrySize > s.maxSize {
+			return ErrSessionTooLarge
+		}
+		s.size += entrySize - s.sizes[key]
+		s.sizes[key] = entrySize
+	}
 	s.data[key] = value
+	s.changed[key] = struct{}{}
+	return nil
 }
 
 // Delete removes a value from the session
@@ -57,6 +148,9 @@ func (s *DefaultSession) Delete(key string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.data, key)
+	s.size -= s.sizes[key]
+	delete(s.sizes, key)
+	s.changed[key] = struct{}{}
 }
 
 // Has checks if a key exists in the session
@@ -67,17 +161,58 @@ func (s *DefaultSession) Has(key string) bool {
 	return ok
 }
 
+// Keys returns all keys stored in the session
+func (s *DefaultSession) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ChangedKeys returns the sorted keys set or deleted since the session was created or last
+// marked saved with MarkSaved, so persistent stores can write only the changed fields.
+// Deleted keys are reported too: Has returns false for them.
+func (s *DefaultSession) ChangedKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.changed))
+	for key := range s.changed {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// MarkSaved resets the changed keys once the session has been persisted
+func (s *DefaultSession) MarkSaved() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.changed)
+}
+
 // Clear removes all data from the session
 func (s *DefaultSession) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	for key := range s.data {
+		s.changed[key] = struct{}{}
+	}
 	s.data = make(map[string]any)
+	s.sizes = make(map[string]int)
+	s.size = 0
 }
 
-// IsExpired returns true if the session has expired based on last access time
+// IsExpired returns true if the session has expired, based on the last access time
+// with IdleTTL or on the creation time with AbsoluteTTL
 func (s *DefaultSession) IsExpired() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	if s.ttlStrategy == AbsoluteTTL {
+		return time.Since(s.createdAt) > s.expirationDuration
+	}
 	return time.Since(s.lastAccessedAt) > s.expirationDuration
 }
 
@@ -109,12 +244,35 @@ func (s *DefaultSession) Update() {
 
 // ExpirationDuration returns the session's expiration duration
 func (s *DefaultSession) ExpirationDuration() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.expirationDuration
 }
 
+// Size returns the total size in bytes of the session data, tracked only when a maximum size is set
+func (s *DefaultSession) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
+
 // SetExpirationDuration sets the session's expiration duration
 func (s *DefaultSession) SetExpirationDuration(d time.Duration) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.expirationDuration = d
 }
+
+// TTLStrategy returns the session's expiration strategy
+func (s *DefaultSession) TTLStrategy() TTLStrategy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ttlStrategy
+}
+
+// SetTTLStrategy sets the session's expiration strategy
+func (s *DefaultSession) SetTTLStrategy(strategy TTLStrategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttlStrategy = strategy
+}
@@ -0,0 +1,81 @@
+package libserver
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDefaultSessionConcurrentAccess hammers a session from concurrent goroutines. Run it with -race.
+func TestDefaultSessionConcurrentAccess(t *testing.T) {
+	t.Parallel()
+	session := NewDefaultSession()
+
+	const goroutines = 16
+	const iterations = 500
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := "key" + strconv.Itoa(g%4)
+			for i := range iterations {
+				if err := session.Set(key, i); err != nil {
+					t.Errorf("Set(%q): %v", key, err)
+					return
+				}
+				session.Get(key)
+				session.Has(key)
+				session.Keys()
+				session.Delete(key)
+				if i%50 == 0 {
+					session.Clear()
+				}
+				session.Update()
+				session.IsExpired()
+				session.SetExpirationDuration(time.Hour)
+				session.ExpirationDuration()
+				session.LastAccessedAt()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestDefaultSessionConcurrentSetGet checks that every goroutine reads back the values it
+// sets under its own key while other goroutines write to the same session
+func TestDefaultSessionConcurrentSetGet(t *testing.T) {
+	t.Parallel()
+	session := NewDefaultSession()
+
+	const goroutines = 16
+	const iterations = 500
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := "key" + strconv.Itoa(g)
+			for i := range iterations {
+				if err := session.Set(key, i); err != nil {
+					t.Errorf("Set(%q): %v", key, err)
+					return
+				}
+				if got := session.Get(key); got != i {
+					t.Errorf("Get(%q) = %v, want %d", key, got, i)
+					return
+				}
+				if !session.Has(key) {
+					t.Errorf("Has(%q) = false after Set", key)
+					return
+				}
+			}
+			session.Delete(key)
+			if session.Has(key) {
+				t.Errorf("Has(%q) = true after Delete", key)
+			}
+		}()
+	}
+	wg.Wait()
+}
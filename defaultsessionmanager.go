@@ -1,10 +1,15 @@
 package libserver
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
 
+// ErrSessionNotFound is returned when a session does not exist
+var ErrSessionNotFound = errors.New("session not found")
+
 // DefaultCleanupInterval is the default interval for cleaning up expired sessions
 const DefaultCleanupInterval = time.Hour
 
@@ -13,9 +18,14 @@ type DefaultSessionManager struct {
 	data              map[string]Session
 	mu                *sync.RWMutex
 	stopCh            chan struct{}
+	done              <-chan struct{}
 	cleanupInterval   time.Duration
 	sessionExpiration time.Duration
 	cleanupRunning    bool
+	idGenerator       IDGenerator
+	maxSessionSize    int
+	ttlStrategy       TTLStrategy
+	eventBus          SessionEventBus
 }
 
 // NewDefaultSessionManager creates a new session manager with default settings
@@ -23,8 +33,19 @@ func NewDefaultSessionManager() *DefaultSessionManager {
 	return NewDefaultSessionManagerWithConfig(DefaultCleanupInterval, DefaultSessionExpiration)
 }
 
+// SessionManagerOption configures a DefaultSessionManager at construction
+type SessionManagerOption func(*DefaultSessionManager)
+
+// WithCleanupContext stops the cleanup goroutine of the session manager when ctx is done,
+// in addition to Stop
+func WithCleanupContext(ctx context.Context) SessionManagerOption {
+	return func(s *DefaultSessionManager) {
+		s.done = ctx.Done()
+	}
+}
+
 // NewDefaultSessionManagerWithConfig creates a new session manager with custom settings
-func NewDefaultSessionManagerWithConfig(cleanupInterval, sessionExpiration time.Duration) *DefaultSessionManager {
+func NewDefaultSessionManagerWithConfig(cleanupInterval, sessionExpiration time.Duration, options ...SessionManagerOption) *DefaultSessionManager {
 	manager := &DefaultSessionManager{
 		data:              make(map[string]Session),
 		mu:                &sync.RWMutex{},
@@ -33,10 +54,20 @@ func NewDefaultSessionManagerWithConfig(cleanupInterval, sessionExpiration time.
 		sessionExpiration: sessionExpiration,
 		cleanupRunning:    false,
 	}
+	for _, option := range options {
+		option(manager)
+	}
 	manager.startCleanup()
 	return manager
 }
 
+// NewDefaultSessionManagerWithContext creates a new session manager with default settings
+// whose cleanup goroutine stops when ctx is done, in addition to Stop. Use
+// NewDefaultSessionManagerWithConfig with WithCleanupContext for custom settings.
+func NewDefaultSessionManagerWithContext(ctx context.Context) *DefaultSessionManager {
+	return NewDefaultSessionManagerWithConfig(DefaultCleanupInterval, DefaultSessionExpiration, WithCleanupContext(ctx))
+}
+
 // startCleanup starts the background goroutine for cleaning up expired sessions
 func (s *DefaultSessionManager) startCleanup() {
 	s.mu.Lock()
@@ -56,6 +87,9 @@ func (s *DefaultSessionManager) startCleanup() {
 				s.cleanup()
 			case <-s.stopCh:
 				return
+			case <-s.done:
+				s.Stop()
+				return
 			}
 		}
 	}()
@@ -73,21 +107,28 @@ func (s *DefaultSessionManager) Stop() {
 
 // CreateSession creates a new session with default expiration
 func (s *DefaultSessionManager) CreateSession() Session {
-	session := NewDefaultSessionWithExpiration(s.sessionExpiration)
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	session := NewDefaultSessionWithOptions(DefaultSessionOptions{
+		Expiration:  s.sessionExpiration,
+		IDGenerator: s.idGenerator,
+		MaxSize:     s.maxSessionSize,
+		TTLStrategy: s.ttlStrategy,
+	})
 	s.data[session.Id()] = session
+	s.mu.Unlock()
+	s.publish(SessionCreated, session.Id())
 	return session
 }
 
 // CreateSessionWithID creates a new session with a specific ID (for session restoration)
 func (s *DefaultSessionManager) CreateSessionWithID(id string) Session {
-	session := NewDefaultSessionWithExpiration(s.sessionExpiration)
-	// Use reflection or create a special constructor - here we create and replace the ID
-	// For simplicity, we create a standard session
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	session := newDefaultSessionWithID(id, s.sessionExpiration)
+	session.maxSize = s.maxSessionSize
+	session.ttlStrategy = s.ttlStrategy
 	s.data[id] = session
+	s.mu.Unlock()
+	s.publish(SessionCreated, id)
 	return session
 }
 
@@ -101,8 +142,12 @@ func (s *DefaultSessionManager) GetSession(id string) Session {
 // DeleteSession removes a session by its ID
 func (s *DefaultSessionManager) DeleteSession(id string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	_, ok := s.data[id]
 	delete(s.data, id)
+	s.mu.Unlock()
+	if ok {
+		s.publish(SessionDeleted, id)
+	}
 }
 
 // HasSession checks if a session exists by its ID
@@ -113,15 +158,31 @@ func (s *DefaultSessionManager) HasSession(id string) bool {
 	return ok
 }
 
+// RenewSession extends the life of an active session by resetting its last access time
+func (s *DefaultSessionManager) RenewSession(id string) error {
+	s.mu.RLock()
+	session, ok := s.data[id]
+	s.mu.RUnlock()
+	if !ok || session.IsExpired() {
+		return ErrSessionNotFound
+	}
+	session.Update()
+	s.publish(SessionUpdated, id)
+	return nil
+}
+
 // cleanup removes all expired sessions
 func (s *DefaultSessionManager) cleanup() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	var expired []string
 	for id, session := range s.data {
 		if session.IsExpired() {
 			delete(s.data, id)
+			expired = append(expired, id)
 		}
 	}
+	s.mu.Unlock()
+	s.publish(SessionExpired, expired...)
 }
 
 // SessionCount returns the number of active sessions
@@ -131,6 +192,27 @@ func (s *DefaultSessionManager) SessionCount() int {
 	return len(s.data)
 }
 
+// SetIDGenerator sets the generator of the IDs of new sessions, UUIDs are used by default
+func (s *DefaultSessionManager) SetIDGenerator(generator IDGenerator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idGenerator = generator
+}
+
+// SetMaxSessionSize sets the maximum data size in bytes of new sessions, 0 for no limit
+func (s *DefaultSessionManager) SetMaxSessionSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSessionSize = size
+}
+
+// SetTTLStrategy sets the expiration strategy of new sessions, IdleTTL by default
+func (s *DefaultSessionManager) SetTTLStrategy(strategy TTLStrategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttlStrategy = strategy
+}
+
 // SetSessionExpiration sets the default expiration for new sessions
 func (s *DefaultSessionManager) SetSessionExpiration(d time.Duration) {
 	s.mu.Lock()
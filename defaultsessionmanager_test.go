@@ -0,0 +1,45 @@
+package libserver
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestDefaultSessionManagerConcurrentCreateGet checks that a created session is immediately
+// retrievable while other goroutines create, read and delete sessions. Run it with -race.
+func TestDefaultSessionManagerConcurrentCreateGet(t *testing.T) {
+	manager := NewDefaultSessionManager()
+	defer manager.Stop()
+
+	const goroutines = 16
+	const iterations = 200
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				session := manager.CreateSession()
+				got := manager.GetSession(session.Id())
+				if got == nil {
+					t.Errorf("GetSession(%q) returned nil right after CreateSession", session.Id())
+					return
+				}
+				if err := got.Set("key", "value"); err != nil {
+					t.Errorf("Set: %v", err)
+					return
+				}
+				if !manager.HasSession(session.Id()) {
+					t.Errorf("HasSession(%q) returned false right after CreateSession", session.Id())
+					return
+				}
+				manager.DeleteSession(session.Id())
+				if manager.GetSession(session.Id()) != nil {
+					t.Errorf("GetSession(%q) returned the session after DeleteSession", session.Id())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
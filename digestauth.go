@@ -0,0 +1,238 @@
+package libserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DigestUsernameKey is the context key for accessing the username authenticated by Digest authentication
+const DigestUsernameKey ContextKey = "digestUsername"
+
+// digestNonceLifetime is the time after which a nonce is reported as stale
+const digestNonceLifetime = 5 * time.Minute
+
+// digestMaxTrackedNonces is the maximum number of nonces whose count is tracked
+const digestMaxTrackedNonces = 10000
+
+// digestNonce tracks the last nonce count used with a nonce that authenticated a request
+type digestNonce struct {
+	issuedAt time.Time
+	count    uint64
+}
+
+// digestNonces issues and validates the nonces of a Digest authentication middleware. Nonces
+// are stateless: they carry their issue time and random bytes, authenticated with an HMAC,
+// so challenging anonymous clients stores nothing. Only the nonces that authenticated a
+// request are tracked, to check their counts, and at most digestMaxTrackedNonces of them.
+type digestNonces struct {
+	secret []byte
+	used   map[string]*digestNonce
+	// evictedBefore makes the nonces issued until then stale, since their counts were dropped
+	evictedBefore time.Time
+	mu            *sync.Mutex
+}
+
+// newDigestNonces creates a nonce issuer with a random secret
+func newDigestNonces() *digestNonces {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return &digestNonces{secret: secret, used: make(map[string]*digestNonce), mu: &sync.Mutex{}}
+}
+
+// mac returns the HMAC of the issue time and random bytes of a nonce
+func (n *digestNonces) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, n.secret)
+	h.Write(payload)
+	return h.Sum(nil)[:16]
+}
+
+// issue creates a new nonce: base64(issue time || random bytes || HMAC)
+func (n *digestNonces) issue() string {
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().UnixNano()))
+	rand.Read(payload[8:])
+	return base64.RawURLEncoding.EncodeToString(append(payload, n.mac(payload)...))
+}
+
+// issuedAt returns the issue time of a nonce, or false if the nonce was not issued by n
+func (n *digestNonces) issuedAt(nonce string) (time.Time, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) != 32 || !hmac.Equal(raw[16:], n.mac(raw[:16])) {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(raw))), true
+}
+
+// use validates the nonce and its count, returning whether the nonce is valid and whether
+// it is stale. The count must increase on every use to prevent replays.
+func (n *digestNonces) use(nonce string, count uint64) (valid, stale bool) {
+	issuedAt, ok := n.issuedAt(nonce)
+	if !ok {
+		return false, false
+	}
+	if time.Since(issuedAt) > digestNonceLifetime {
+		return false, true
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	state, ok := n.used[nonce]
+	if !ok {
+		if len(n.used) >= digestMaxTrackedNonces {
+			n.evict()
+		}
+		if !issuedAt.After(n.evictedBefore) {
+			return false, true
+		}
+		state = &digestNonce{issuedAt: issuedAt}
+		n.used[nonce] = state
+	}
+	if count <= state.count {
+		return false, false
+	}
+	state.count = count
+	return true, false
+}
+
+// evict drops the expired nonces and, if that is not enough, the older half of the tracked
+// ones, whose later uses are then reported as stale
+func (n *digestNonces) evict() {
+	for value, state := range n.used {
+		if time.Since(state.issuedAt) > digestNonceLifetime {
+			delete(n.used, value)
+		}
+	}
+	if len(n.used) < digestMaxTrackedNonces {
+		return
+	}
+	issued := make([]time.Time, 0, len(n.used))
+	for _, state := range n.used {
+		issued = append(issued, state.issuedAt)
+	}
+	slices.SortFunc(issued, time.Time.Compare)
+	n.evictedBefore = issued[len(issued)/2]
+	for value, state := range n.used {
+		if !state.issuedAt.After(n.evictedBefore) {
+			delete(n.used, value)
+		}
+	}
+}
+
+// parseDigestAuthorization parses the parameters of a Digest Authorization header
+func parseDigestAuthorization(header string) (map[string]string, bool) {
+	rest, ok := strings.CutPrefix(header, "Digest ")
+	if !ok {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			return nil, false
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			end := strings.IndexByte(value, ',')
+			if end < 0 {
+				end = len(value)
+			}
+			params[key] = strings.TrimSpace(value[:end])
+			rest = value[end:]
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+	}
+	return params, true
+}
+
+// md5Hex returns the hexadecimal MD5 digest of the string
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// DigestAuthMiddleware protects handlers with HTTP Digest authentication (RFC 7616) using
+// MD5 and qop=auth. ha1Fn returns the precomputed HA1 = MD5(username:realm:password) of a
+// user, so plain-text passwords never need to be stored. Nonces expire after 5 minutes and
+// are then reported as stale so clients retry transparently; nonce counts must increase to
+// prevent replays. Nonces are signed rather than stored, so unauthenticated requests cost
+// no memory. The authenticated username is injected into the request context.
+func DigestAuthMiddleware(realm string, ha1Fn func(username string) (ha1 string, found bool)) func(http.Handler) http.Handler {
+	nonces := newDigestNonces()
+	opaque, _ := randomToken()
+
+	challenge := func(w http.ResponseWriter, stale bool) {
+		value := fmt.Sprintf(`Digest realm=%q, qop="auth", algorithm=MD5, nonce=%q, opaque=%q`, realm, nonces.issue(), opaque)
+		if stale {
+			value += ", stale=true"
+		}
+		w.Header().Set("WWW-Authenticate", value)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params, ok := parseDigestAuthorization(r.Header.Get("Authorization"))
+			if !ok || params["realm"] != realm || params["opaque"] != opaque || params["qop"] != "auth" ||
+				params["uri"] != r.URL.RequestURI() ||
+				(params["algorithm"] != "" && !strings.EqualFold(params["algorithm"], "MD5")) {
+				challenge(w, false)
+				return
+			}
+			count, err := strconv.ParseUint(params["nc"], 16, 64)
+			if err != nil {
+				challenge(w, false)
+				return
+			}
+			ha1, found := ha1Fn(params["username"])
+			if !found {
+				challenge(w, false)
+				return
+			}
+
+			ha2 := md5Hex(r.Method + ":" + params["uri"])
+			expected := md5Hex(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], "auth", ha2}, ":"))
+			if subtle.ConstantTimeCompare([]byte(expected), []byte(params["response"])) != 1 {
+				challenge(w, false)
+				return
+			}
+			// The response is valid, check the nonce last so that a stale nonce is only
+			// reported to clients that know the password
+			if valid, stale := nonces.use(params["nonce"], count); !valid {
+				challenge(w, stale)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), DigestUsernameKey, params["username"])
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetDigestUsernameFromContext retrieves the username authenticated by DigestAuthMiddleware
+func GetDigestUsernameFromContext(ctx context.Context) string {
+	if username, ok := ctx.Value(DigestUsernameKey).(string); ok {
+		return username
+	}
+	return ""
+}
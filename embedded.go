@@ -0,0 +1,77 @@
+package libserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// embeddedETags computes the ETag of every file of the filesystem from its content hash
+func embeddedETags(fsys fs.FS) (map[string]string, error) {
+	etags := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		etags["/"+name] = `"` + hex.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	return etags, err
+}
+
+// ServeEmbedded serves the files of the embedded filesystem under root at urlPrefix.
+// ETags are derived from the file contents when ServeEmbedded is called, so clients
+// revalidating with If-None-Match get 304 Not Modified responses.
+func (s *WebServer) ServeEmbedded(urlPrefix string, efs embed.FS, root string) error {
+	sub, err := fs.Sub(efs, root)
+	if err != nil {
+		return err
+	}
+	etags, err := embeddedETags(sub)
+	if err != nil {
+		return err
+	}
+
+	fileServer := http.FileServer(http.FS(sub))
+	prefix := strings.TrimSuffix(urlPrefix, "/")
+	s.AddHandler(prefix+"/", http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+		if etag, ok := etags[name]; ok {
+			w.Header().Set("ETag", etag)
+		}
+		fileServer.ServeHTTP(w, r)
+	})))
+	return nil
+}
+
+// AddEmbeddedFileEndpoint serves a single file of the embedded filesystem at pattern.
+// The Content-Type is derived from the file extension and the ETag from the file content.
+func (s *WebServer) AddEmbeddedFileEndpoint(pattern string, efs embed.FS, filePath string) error {
+	content, err := efs.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	name := path.Base(filePath)
+
+	s.AddHandlerFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+	})
+	return nil
+}
@@ -0,0 +1,68 @@
+package libserver
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// noListingFileSystem is an http.FileSystem refusing to open directories without an index.html,
+// so that http.FileServer responds with 403 Forbidden instead of listing their content
+type noListingFileSystem struct {
+	fs http.FileSystem
+}
+
+// Open opens the named file, returning fs.ErrPermission for directories without index
+func (nfs noListingFileSystem) Open(name string) (http.File, error) {
+	f, err := nfs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		index, err := nfs.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			return nil, fs.ErrPermission
+		}
+		index.Close()
+	}
+	return f, nil
+}
+
+// NewFileServer returns a handler serving files from fsRoot. When allowDirListing is false,
+// requests for directories without an index.html are answered with 403 Forbidden.
+func NewFileServer(fsRoot string, allowDirListing bool) http.Handler {
+	var fileSystem http.FileSystem = http.Dir(fsRoot)
+	if !allowDirListing {
+		fileSystem = noListingFileSystem{fs: fileSystem}
+	}
+	return http.FileServer(fileSystem)
+}
+
+// AddFileServer serves the files from fsRoot under urlPrefix. Requests go through the
+// session injection like any other handler, so the session is available to wrapping
+// handlers restricting access to the files.
+func (s *WebServer) AddFileServer(urlPrefix, fsRoot string, allowDirListing bool) {
+	prefix := strings.TrimSuffix(urlPrefix, "/")
+	s.AddHandler(prefix+"/", http.StripPrefix(prefix, NewFileServer(fsRoot, allowDirListing)))
+}
+
+// AddFileEndpoint serves a single file, such as /robots.txt or /favicon.ico, at pattern.
+// The Content-Type is derived from the file extension and the ETag from the size and
+// modification time of the file, so clients revalidating get 304 Not Modified responses.
+func (s *WebServer) AddFileEndpoint(pattern, filePath string) {
+	s.AddHandlerFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if stat, err := os.Stat(filePath); err == nil && !stat.IsDir() {
+			w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, stat.Size(), stat.ModTime().UnixNano()))
+		}
+		http.ServeFile(w, r, filePath)
+	})
+}
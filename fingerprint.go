@@ -0,0 +1,137 @@
+package libserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RequestFingerprintKey is the context key for accessing the RequestFingerprint
+const RequestFingerprintKey ContextKey = "requestFingerprint"
+
+// clientHelloKey is the connection context key of the clientHello filled during the handshake
+const clientHelloKey ContextKey = "libserver.clientHello"
+
+// clientHello holds what is kept from the TLS ClientHello of a connection
+type clientHello struct {
+	ja3 string
+}
+
+// RequestFingerprint describes the client of a request, to tell browsers from bots
+type RequestFingerprint struct {
+	// JA3 is the MD5 hash of the JA3 TLS client fingerprint, empty if the request was not
+	// received over HTTPS by a WebServer
+	JA3 string
+	// Headers are the canonical names of the request headers, sorted since net/http does
+	// not preserve the order in which the client sent them
+	Headers []string
+	// UserAgent is the User-Agent header
+	UserAgent string
+	// Accept is the Accept header
+	Accept string
+	// Hash is the MD5 hash of all the above, identifying clients sharing the same fingerprint
+	Hash string
+}
+
+// isGREASE returns true for the GREASE values (RFC 8701) ignored by JA3
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+// joinJA3 joins the values of a JA3 field with dashes, skipping the GREASE values
+func joinJA3[T ~uint8 | ~uint16](values []T) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		if !isGREASE(uint16(value)) {
+			parts = append(parts, strconv.Itoa(int(value)))
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// ja3 returns the JA3 hash of a ClientHello. TLS 1.3 clients announce TLS 1.2 as their
+// legacy version, so the version is the highest supported one capped at TLS 1.2.
+func ja3(hello *tls.ClientHelloInfo) string {
+	var version uint16
+	for _, v := range hello.SupportedVersions {
+		if !isGREASE(v) && v > version {
+			version = min(v, tls.VersionTLS12)
+		}
+	}
+	return md5Hex(strings.Join([]string{
+		strconv.Itoa(int(version)),
+		joinJA3(hello.CipherSuites),
+		joinJA3(hello.Extensions),
+		joinJA3(hello.SupportedCurves),
+		joinJA3(hello.SupportedPoints),
+	}, ","))
+}
+
+// configureFingerprinting records the JA3 fingerprint of the TLS connections of the server
+// in their connection context, unless the callbacks it needs are already set
+func configureFingerprinting(server *http.Server) {
+	if server.ConnContext != nil || server.TLSConfig.GetConfigForClient != nil {
+		return
+	}
+	server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return context.WithValue(ctx, clientHelloKey, &clientHello{})
+	}
+	server.TLSConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		// The handshake runs with the connection context, before any request is read
+		if holder, ok := hello.Context().Value(clientHelloKey).(*clientHello); ok {
+			holder.ja3 = ja3(hello)
+		}
+		return nil, nil
+	}
+}
+
+// NewRequestFingerprint computes the fingerprint of a request
+func NewRequestFingerprint(r *http.Request) RequestFingerprint {
+	fingerprint := RequestFingerprint{
+		Headers:   make([]string, 0, len(r.Header)),
+		UserAgent: r.UserAgent(),
+		Accept:    r.Header.Get("Accept"),
+	}
+	if holder, ok := r.Context().Value(clientHelloKey).(*clientHello); ok {
+		fingerprint.JA3 = holder.ja3
+	}
+	for name := range r.Header {
+		fingerprint.Headers = append(fingerprint.Headers, name)
+	}
+	slices.Sort(fingerprint.Headers)
+	fingerprint.Hash = md5Hex(strings.Join([]string{
+		fingerprint.JA3,
+		strings.Join(fingerprint.Headers, ","),
+		fingerprint.UserAgent,
+		fingerprint.Accept,
+	}, "|"))
+	return fingerprint
+}
+
+// RequestFingerprintMiddleware computes the fingerprint of every request and passes it to fn,
+// answering 403 Forbidden when fn returns false. The fingerprint is injected into the request
+// context for downstream analytics. The JA3 fingerprint is only available for requests served
+// over HTTPS by a WebServer whose TLS config has no ConnContext or GetConfigForClient callback.
+func RequestFingerprintMiddleware(fn func(fingerprint RequestFingerprint, r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fingerprint := NewRequestFingerprint(r)
+			if !fn(fingerprint, r) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			ctx := context.WithValue(r.Context(), RequestFingerprintKey, fingerprint)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetRequestFingerprintFromContext retrieves the fingerprint computed by RequestFingerprintMiddleware
+func GetRequestFingerprintFromContext(ctx context.Context) (RequestFingerprint, bool) {
+	fingerprint, ok := ctx.Value(RequestFingerprintKey).(RequestFingerprint)
+	return fingerprint, ok
+}
@@ -0,0 +1,23 @@
+package libserver
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// EnableH2C enables HTTP/2 over cleartext connections (h2c), for internal services such as
+// gRPC-over-HTTP/2 clients that connect without TLS. It has no effect when HTTPS is enabled,
+// since HTTP/2 is then negotiated by TLS.
+func (s *WebServer) EnableH2C() {
+	s.h2c = true
+}
+
+// withH2C wraps the handler to accept h2c connections when enabled
+func (s *WebServer) withH2C(handler http.Handler) http.Handler {
+	if !s.h2c || s.withHttps {
+		return handler
+	}
+	return h2c.NewHandler(handler, &http2.Server{})
+}
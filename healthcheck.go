@@ -0,0 +1,93 @@
+package libserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DefaultHealthCheckPath is the path of the health endpoint when AddHealthCheckFunc gets no path
+const DefaultHealthCheckPath = "/healthz"
+
+// healthCheck is a named check of a health endpoint
+type healthCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// healthEndpoint is the set of checks served at a path
+type healthEndpoint struct {
+	checks []healthCheck
+	mu     *sync.RWMutex
+}
+
+// healthResponse is the body of a health endpoint response
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// AddHealthCheckFunc registers fn as a check named name of the health endpoint served at
+// path, DefaultHealthCheckPath when empty. Checks added with the same path are aggregated:
+// the endpoint runs them concurrently with the request context and answers 200 OK when
+// they all succeed, or 503 Service Unavailable otherwise, with a JSON body giving the
+// result of each check. A check that panics fails with the panic value. Requests to the
+// endpoint do not create sessions. It returns ErrCustomRouter if the server uses a custom
+// router, see WithRouter, and the errors of Register if path conflicts with a route.
+func (s *WebServer) AddHealthCheckFunc(name, path string, fn func(ctx context.Context) error) error {
+	if path == "" {
+		path = DefaultHealthCheckPath
+	}
+	endpoint, ok := s.healthChecks[path]
+	if !ok {
+		endpoint = &healthEndpoint{mu: &sync.RWMutex{}}
+		if err := s.registerEndpoint(s.mux, "GET "+path, endpoint); err != nil {
+			return err
+		}
+		if s.healthChecks == nil {
+			s.healthChecks = make(map[string]*healthEndpoint)
+		}
+		s.healthChecks[path] = endpoint
+	}
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	endpoint.checks = append(endpoint.checks, healthCheck{name: name, fn: fn})
+	return nil
+}
+
+// ServeHTTP runs the checks and writes their results
+func (e *healthEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	checks := e.checks
+	e.mu.RUnlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if rec := recover(); rec != nil {
+					errs[i] = fmt.Errorf("panic: %v", rec)
+				}
+			}()
+			errs[i] = check.fn(r.Context())
+		}()
+	}
+	wg.Wait()
+
+	response := healthResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	statusCode := http.StatusOK
+	for i, check := range checks {
+		response.Checks[check.name] = "ok"
+		if errs[i] != nil {
+			response.Checks[check.name] = errs[i].Error()
+			response.Status = "error"
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, statusCode, response)
+}
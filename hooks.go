@@ -0,0 +1,31 @@
+package libserver
+
+import "net/http"
+
+// OnRequest registers a hook called on every request before the middleware and the handler,
+// with the session and the server data already in the request context. Hooks are called in
+// registration order; they are lighter than middleware but cannot alter the response.
+func (s *WebServer) OnRequest(fn func(r *http.Request)) {
+	s.requestHooks = append(s.requestHooks, fn)
+}
+
+// runRequestHooks calls the hooks registered with OnRequest
+func (s *WebServer) runRequestHooks(r *http.Request) {
+	for _, hook := range s.requestHooks {
+		hook(r)
+	}
+}
+
+// OnResponse registers a hook called on every request after the handler returned, with the
+// status code and the number of bytes of the response body. Hooks are called in reverse
+// registration order, the last one registered first, like the innermost middleware.
+func (s *WebServer) OnResponse(fn func(r *http.Request, statusCode int, bytesWritten int64)) {
+	s.responseHooks = append(s.responseHooks, fn)
+}
+
+// runResponseHooks calls the hooks registered with OnResponse
+func (s *WebServer) runResponseHooks(r *http.Request, rw *responseWriter) {
+	for i := len(s.responseHooks) - 1; i >= 0; i-- {
+		s.responseHooks[i](r, rw.statusCode, rw.bytesWritten)
+	}
+}
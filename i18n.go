@@ -0,0 +1,94 @@
+package libserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// LanguageKey is the context key for accessing the negotiated language
+const LanguageKey ContextKey = "language"
+
+// Language match kinds, from the weakest to the strongest
+const (
+	languageMatchNone = iota
+	languageMatchWildcard
+	languageMatchPrefix
+	languageMatchExact
+)
+
+// matchLanguage returns how well an Accept-Language range matches a supported locale
+func matchLanguage(languageRange, locale string) int {
+	languageRange = strings.ToLower(languageRange)
+	locale = strings.ToLower(locale)
+	switch {
+	case languageRange == locale:
+		return languageMatchExact
+	case languageRange == "*":
+		return languageMatchWildcard
+	case strings.HasPrefix(locale, languageRange+"-"), strings.HasPrefix(languageRange, locale+"-"):
+		return languageMatchPrefix
+	default:
+		return languageMatchNone
+	}
+}
+
+// NegotiateLanguage returns the supported locale best matching the request's Accept-Language
+// header, or fallback if none matches. Locales are scored by quality factor, then exact matches
+// win over prefix matches (e.g. "en-GB" matching "en"), then earlier supported locales win.
+func NegotiateLanguage(r *http.Request, supported []string, fallback string) string {
+	best := fallback
+	bestQuality, bestMatch, bestIndex := 0.0, languageMatchNone, len(supported)
+
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		languageRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		languageRange = strings.TrimSpace(languageRange)
+		if languageRange == "" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		for i, locale := range supported {
+			match := matchLanguage(languageRange, locale)
+			if match == languageMatchNone {
+				continue
+			}
+			if quality > bestQuality ||
+				(quality == bestQuality && match > bestMatch) ||
+				(quality == bestQuality && match == bestMatch && i < bestIndex) {
+				best, bestQuality, bestMatch, bestIndex = locale, quality, match, i
+			}
+		}
+	}
+	return best
+}
+
+// I18nMiddleware negotiates the request language and injects it into the request context
+func I18nMiddleware(supported []string, fallback string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			language := NegotiateLanguage(r, supported, fallback)
+			w.Header().Add("Vary", "Accept-Language")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), LanguageKey, language)))
+		})
+	}
+}
+
+// GetLanguageFromContext retrieves the negotiated language from a request context
+func GetLanguageFromContext(ctx context.Context) string {
+	if language, ok := ctx.Value(LanguageKey).(string); ok {
+		return language
+	}
+	return ""
+}
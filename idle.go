@@ -0,0 +1,25 @@
+package libserver
+
+import (
+	"context"
+	"time"
+)
+
+// idlePollInterval is how often WaitForIdle checks the in-flight request counter
+const idlePollInterval = 5 * time.Millisecond
+
+// WaitForIdle blocks until no request is being handled, or ctx is done. A client may get its
+// response before the handler returns, so tests checking the side effects of a handler can
+// call WaitForIdle after the request instead of sleeping.
+func (s *WebServer) WaitForIdle(ctx context.Context) error {
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for s.stats.inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
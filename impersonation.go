@@ -0,0 +1,94 @@
+package libserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// libserverSessionKeyPrefix prefixes the session keys used by libserver itself
+const libserverSessionKeyPrefix = "libserver."
+
+// impersonatorSessionKey is the session key holding the ID of the impersonating session
+const impersonatorSessionKey = "libserver.impersonator"
+
+// ErrNotImpersonating is returned by EndImpersonation when the session is not an impersonation
+var ErrNotImpersonating = errors.New("session is not impersonating another one")
+
+// ErrSessionNotCopyable is returned when the data of a session cannot be listed to be copied
+var ErrSessionNotCopyable = errors.New("session does not support listing its keys")
+
+// ImpersonateSession lets an admin act as another user: it creates an impersonation session
+// holding a copy of the data of the target session, records the current (admin) session ID
+// in it and points the session cookie of the request to it. The target session itself is
+// not modified and the admin session is kept, so EndImpersonation can switch back to it.
+// Data stored by libserver under libserver.* keys is not copied. It returns the
+// impersonation session and the admin session.
+func (s *WebServer) ImpersonateSession(w http.ResponseWriter, r *http.Request, targetSessionID string) (Session, Session, error) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	original := GetSession(r.Context())
+	scope := s.requestSessionScope(r)
+	if original == nil || scope.manager == nil {
+		return nil, nil, ErrSessionNotFound
+	}
+	tenant := s.resolveTenant(r)
+	target := scope.manager.GetSession(targetSessionID)
+	if target == nil || target.IsExpired() || !belongsToTenant(target, tenant) {
+		return nil, nil, ErrSessionNotFound
+	}
+	lister, ok := target.(interface{ Keys() []string })
+	if !ok {
+		return nil, nil, ErrSessionNotCopyable
+	}
+
+	impersonation, err := s.createTenantSession(scope.manager, tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, key := range lister.Keys() {
+		if strings.HasPrefix(key, libserverSessionKeyPrefix) {
+			continue
+		}
+		if err := impersonation.Set(key, target.Get(key)); err != nil {
+			scope.manager.DeleteSession(impersonation.Id())
+			return nil, nil, err
+		}
+	}
+	if err := impersonation.Set(impersonatorSessionKey, original.Id()); err != nil {
+		scope.manager.DeleteSession(impersonation.Id())
+		return nil, nil, err
+	}
+	http.SetCookie(w, scope.sessionCookie(s, scope.cookieName(s, tenant), impersonation.Id()))
+	return impersonation, original, nil
+}
+
+// EndImpersonation ends an impersonation started with ImpersonateSession: the impersonation
+// session is deleted and the session cookie points back to the original session, which is
+// returned. Changes made to the impersonation session are not copied to the target session.
+func (s *WebServer) EndImpersonation(w http.ResponseWriter, r *http.Request) (Session, error) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	impersonation := GetSession(r.Context())
+	scope := s.requestSessionScope(r)
+	if impersonation == nil || scope.manager == nil {
+		return nil, ErrSessionNotFound
+	}
+	originalID, _ := impersonation.Get(impersonatorSessionKey).(string)
+	if originalID == "" {
+		return nil, ErrNotImpersonating
+	}
+	original := scope.manager.GetSession(originalID)
+	if original == nil || original.IsExpired() {
+		return nil, ErrSessionNotFound
+	}
+
+	scope.manager.DeleteSession(impersonation.Id())
+	http.SetCookie(w, scope.sessionCookie(s, scope.cookieName(s, s.resolveTenant(r)), original.Id()))
+	return original, nil
+}
+
+// IsImpersonating returns true if the session was created by ImpersonateSession
+func IsImpersonating(session Session) bool {
+	return session != nil && session.Has(impersonatorSessionKey)
+}
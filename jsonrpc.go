@@ -0,0 +1,149 @@
+package libserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is the code of the errors returned by methods that are not a *JSONRPCError
+	JSONRPCServerError = -32000
+)
+
+// JSONRPCError is a JSON-RPC 2.0 error. Methods may return it to choose the error code,
+// e.g. JSONRPCInvalidParams when their parameters cannot be decoded.
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Error returns the error message
+func (e *JSONRPCError) Error() string {
+	return e.Message
+}
+
+// jsonrpcRequest is a JSON-RPC 2.0 request, the ID is absent for notifications
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// jsonrpcResponse is a JSON-RPC 2.0 response
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// jsonrpcNullID is the ID of responses to requests whose ID could not be read
+var jsonrpcNullID = json.RawMessage("null")
+
+// jsonrpcErrorResponse returns an error response for the request ID
+func jsonrpcErrorResponse(id json.RawMessage, code int, message string) *jsonrpcResponse {
+	if len(id) == 0 {
+		id = jsonrpcNullID
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Error: &JSONRPCError{Code: code, Message: message}, ID: id}
+}
+
+// callJSONRPC calls the method of a request, returning nil for notifications
+func callJSONRPC(methods map[string]func(params json.RawMessage) (any, error), raw json.RawMessage) (response *jsonrpcResponse) {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return jsonrpcErrorResponse(req.ID, JSONRPCInvalidRequest, "Invalid Request")
+	}
+	notification := len(req.ID) == 0
+	method, ok := methods[req.Method]
+	if !ok {
+		if notification {
+			return nil
+		}
+		return jsonrpcErrorResponse(req.ID, JSONRPCMethodNotFound, "Method not found")
+	}
+
+	defer func() {
+		if recover() != nil && !notification {
+			response = jsonrpcErrorResponse(req.ID, JSONRPCInternalError, "Internal error")
+		}
+	}()
+	result, err := method(req.Params)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*JSONRPCError)
+		if !ok {
+			rpcErr = &JSONRPCError{Code: JSONRPCServerError, Message: err.Error()}
+		}
+		return &jsonrpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: req.ID}
+	}
+	if result == nil {
+		// The result member is required on success
+		result = json.RawMessage("null")
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+// JSONRPCHandler serves JSON-RPC 2.0 requests, including batches, sent with POST. Each
+// request is dispatched to the method of the same name, and its result or error is returned
+// in the response envelope. Notifications (requests without ID) get no response; if a request
+// only holds notifications, the handler answers 204 No Content. Methods decode their params
+// themselves and may return a *JSONRPCError to choose the error code.
+func JSONRPCHandler(methods map[string]func(params json.RawMessage) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		var response any
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			var batch []json.RawMessage
+			if err := json.Unmarshal(body, &batch); err != nil {
+				response = jsonrpcErrorResponse(nil, JSONRPCParseError, "Parse error")
+			} else if len(batch) == 0 {
+				response = jsonrpcErrorResponse(nil, JSONRPCInvalidRequest, "Invalid Request")
+			} else {
+				responses := make([]*jsonrpcResponse, 0, len(batch))
+				for _, raw := range batch {
+					if resp := callJSONRPC(methods, raw); resp != nil {
+						responses = append(responses, resp)
+					}
+				}
+				if len(responses) > 0 {
+					response = responses
+				}
+			}
+		} else if !json.Valid(body) {
+			response = jsonrpcErrorResponse(nil, JSONRPCParseError, "Parse error")
+		} else if resp := callJSONRPC(methods, body); resp != nil {
+			response = resp
+		}
+
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+}
@@ -0,0 +1,88 @@
+package libserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// jsonSchemaResource is the URL under which the middleware schema is compiled
+const jsonSchemaResource = "libserver://request-schema.json"
+
+// SchemaValidationError describes a single JSON schema validation failure
+type SchemaValidationError struct {
+	InstanceLocation string `json:"instanceLocation"`
+	KeywordLocation  string `json:"keywordLocation"`
+	Message          string `json:"message"`
+}
+
+// schemaValidationResponse is the body of a 422 response sent for an invalid request
+type schemaValidationResponse struct {
+	Error  string                  `json:"error"`
+	Errors []SchemaValidationError `json:"errors"`
+}
+
+// ValidateJSONSchemaMiddleware validates the JSON request body against a JSON Schema.
+// The schema is compiled once, and the middleware panics if it is invalid. Invalid
+// bodies are rejected with 422 Unprocessable Entity and a JSON body listing the
+// failures; the body remains readable by the handler.
+func ValidateJSONSchemaMiddleware(schema []byte) func(http.Handler) http.Handler {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(jsonSchemaResource, bytes.NewReader(schema)); err != nil {
+		panic(fmt.Sprintf("libserver: invalid JSON schema: %v", err))
+	}
+	compiled, err := compiler.Compile(jsonSchemaResource)
+	if err != nil {
+		panic(fmt.Sprintf("libserver: invalid JSON schema: %v", err))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return BufferBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decoder := json.NewDecoder(bytes.NewReader(GetRequestBodyFromContext(r.Context())))
+			decoder.UseNumber()
+			var document any
+			if err := decoder.Decode(&document); err != nil {
+				writeSchemaValidationResponse(w, http.StatusBadRequest, "invalid JSON body", nil)
+				return
+			}
+
+			if err := compiled.Validate(document); err != nil {
+				var validationErr *jsonschema.ValidationError
+				if !errors.As(err, &validationErr) {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				writeSchemaValidationResponse(w, http.StatusUnprocessableEntity,
+					"request body does not match the schema", collectSchemaErrors(validationErr, nil))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// collectSchemaErrors flattens the leaf causes of a validation error
+func collectSchemaErrors(err *jsonschema.ValidationError, errs []SchemaValidationError) []SchemaValidationError {
+	if len(err.Causes) == 0 {
+		return append(errs, SchemaValidationError{
+			InstanceLocation: err.InstanceLocation,
+			KeywordLocation:  err.KeywordLocation,
+			Message:          err.Message,
+		})
+	}
+	for _, cause := range err.Causes {
+		errs = collectSchemaErrors(cause, errs)
+	}
+	return errs
+}
+
+// writeSchemaValidationResponse writes a JSON error response
+func writeSchemaValidationResponse(w http.ResponseWriter, statusCode int, message string, errs []SchemaValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(schemaValidationResponse{Error: message, Errors: errs})
+}
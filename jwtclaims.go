@@ -0,0 +1,17 @@
+package libserver
+
+import "github.com/golang-jwt/jwt/v5"
+
+// ImportJWTClaims copies JWT claim values into the session. The mapping associates claim
+// names with session keys, e.g. {"sub": "userID", "email": "email"}. Claims missing from
+// the mapping or from the token are ignored. The first error returned by the session is returned.
+func ImportJWTClaims(session Session, claims jwt.MapClaims, mapping map[string]string) error {
+	for claim, key := range mapping {
+		if value, ok := claims[claim]; ok {
+			if err := session.Set(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package libserver
+
+import (
+	"log/slog"
+)
+
+// SetLogger sets the structured logger of the server's built-in events (listeners started,
+// server stopped, route registration, session events, background task failures, debug
+// mode), slog.Default() by default. Route registration and session events are logged at
+// the debug level. Standalone middleware, which is not tied to a server, logs with
+// slog.Default().
+func (s *WebServer) SetLogger(logger *slog.Logger) {
+	s.slogger = logger
+}
+
+// logger returns the logger of the server
+func (s *WebServer) logger() *slog.Logger {
+	if s.slogger != nil {
+		return s.slogger
+	}
+	return slog.Default()
+}
+
+// logRoute logs the registration of a route
+func (s *WebServer) logRoute(pattern string) {
+	s.logger().Debug("libserver: route registered", "pattern", pattern)
+}
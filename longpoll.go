@@ -0,0 +1,100 @@
+package libserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// longPollQueueKeyPrefix is the session key prefix under which long-polling queues are stored
+const longPollQueueKeyPrefix = "libserver.longpoll."
+
+// longPollQueuesMu serializes queue creation so concurrent requests share the same queue
+var longPollQueuesMu sync.Mutex
+
+// LongPollQueue is a message queue consumed by long-polling clients
+type LongPollQueue struct {
+	messages []any
+	notify   chan struct{}
+	mu       *sync.Mutex
+}
+
+// NewLongPollQueue creates a new empty long-polling queue
+func NewLongPollQueue() *LongPollQueue {
+	return &LongPollQueue{
+		notify: make(chan struct{}),
+		mu:     &sync.Mutex{},
+	}
+}
+
+// GetOrCreateQueue returns the named queue stored in the session, creating it if needed.
+// It returns the error of the session if a new queue cannot be stored in it, since messages
+// pushed to a queue missing from the session would never be polled.
+func GetOrCreateQueue(session Session, name string) (*LongPollQueue, error) {
+	longPollQueuesMu.Lock()
+	defer longPollQueuesMu.Unlock()
+	key := longPollQueueKeyPrefix + name
+	if queue, ok := session.Get(key).(*LongPollQueue); ok {
+		return queue, nil
+	}
+	queue := NewLongPollQueue()
+	if err := session.Set(key, queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// Push enqueues a message and wakes up any waiting Poll call
+func (q *LongPollQueue) Push(msg any) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, msg)
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// Len returns the number of pending messages
+func (q *LongPollQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// pop removes the first message, or returns a channel closed on the next Push if the queue is empty
+func (q *LongPollQueue) pop() (any, bool, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.messages) == 0 {
+		return nil, false, q.notify
+	}
+	msg := q.messages[0]
+	q.messages[0] = nil
+	q.messages = q.messages[1:]
+	return msg, true, nil
+}
+
+// Poll blocks until a message is available or the timeout expires.
+// The message is written as JSON; a 204 No Content response is sent on timeout.
+func (q *LongPollQueue) Poll(w http.ResponseWriter, r *http.Request, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		msg, ok, notify := q.pop()
+		if ok {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(msg); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package libserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned by Write when the response exceeds the configured size limit
+var ErrResponseTooLarge = errors.New("response exceeded size limit")
+
+// limitedResponseWriter rejects writes going over a maximum response size
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	request     *http.Request
+	maxBytes    int64
+	written     int64
+	wroteHeader bool
+	exceeded    bool
+}
+
+// WriteHeader records that the header was sent
+func (w *limitedResponseWriter) WriteHeader(statusCode int) {
+	if w.exceeded {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the data unless the size limit would be exceeded
+func (w *limitedResponseWriter) Write(b []byte) (int, error) {
+	if w.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+	if w.written+int64(len(b)) > w.maxBytes {
+		w.exceeded = true
+		slog.WarnContext(w.request.Context(), "libserver: response exceeded the size limit",
+			"method", w.request.Method, "path", w.request.URL.Path, "limit", w.maxBytes)
+		if w.wroteHeader {
+			// Part of the response was already sent, abort the connection so the
+			// client does not mistake a truncated response for a complete one
+			panic(http.ErrAbortHandler)
+		}
+		http.Error(w.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return 0, ErrResponseTooLarge
+	}
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying response writer, for use by http.ResponseController
+func (w *limitedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MaxResponseSizeMiddleware limits the size of response bodies to maxBytes.
+// When a handler writes too much before anything was sent, a 500 response is
+// returned instead and a warning is logged; if part of the response was already
+// sent, the connection is aborted.
+func MaxResponseSizeMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&limitedResponseWriter{
+				ResponseWriter: w,
+				request:        r,
+				maxBytes:       maxBytes,
+			}, r)
+		})
+	}
+}
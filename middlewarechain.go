@@ -0,0 +1,55 @@
+package libserver
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// routeChain is the handler of a route wrapped with the global middleware. The chain is
+// built on the first request and rebuilt only when the middleware configuration changes,
+// so middleware keeping state in the handler they return (limiters, caches, counters)
+// keep it across requests.
+type routeChain struct {
+	handler http.Handler
+	built   atomic.Pointer[builtChain]
+	mu      *sync.Mutex
+}
+
+// builtChain is a chain built for a version of the middleware configuration
+type builtChain struct {
+	chain   http.Handler
+	version uint64
+}
+
+// newRouteChain creates the chain of the given route handler
+func newRouteChain(handler http.Handler) *routeChain {
+	return &routeChain{
+		handler: handler,
+		mu:      &sync.Mutex{},
+	}
+}
+
+// get returns the chain for the current middleware configuration, building it if needed.
+// The configuration lock must be held for reading.
+func (c *routeChain) get(s *WebServer) http.Handler {
+	version := s.chainVersion.Load()
+	if built := c.built.Load(); built != nil && built.version == version {
+		return built.chain
+	}
+	// Build the chain once, even when concurrent requests find it outdated
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if built := c.built.Load(); built != nil && built.version == version {
+		return built.chain
+	}
+	built := &builtChain{chain: s.applyMiddleware(c.handler), version: version}
+	c.built.Store(built)
+	return built.chain
+}
+
+// invalidateChains makes the routes rebuild their chain on their next request, after a
+// change of the settings applyMiddleware depends on
+func (s *WebServer) invalidateChains() {
+	s.chainVersion.Add(1)
+}
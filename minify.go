@@ -0,0 +1,158 @@
+package libserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMinifyContentTypes are the content types minified when none are given
+var DefaultMinifyContentTypes = []string{"text/html", "application/json"}
+
+// htmlRawTextElements are the elements whose content is kept as is by the HTML minifier
+var htmlRawTextElements = []string{"pre", "textarea", "script", "style"}
+
+// minifyJSON removes the insignificant whitespace of a JSON document, which is returned
+// unchanged if it is invalid
+func minifyJSON(src []byte) []byte {
+	var out bytes.Buffer
+	if err := json.Compact(&out, src); err != nil {
+		return src
+	}
+	return out.Bytes()
+}
+
+// minifyHTML removes the comments of an HTML document, except conditional comments, and
+// collapses runs of whitespace between tags into a single space. Tags and the content of
+// pre, textarea, script and style elements are kept as is.
+func minifyHTML(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	for i := 0; i < len(src); {
+		switch {
+		case bytes.HasPrefix(src[i:], []byte("<!--")):
+			end := bytes.Index(src[i+4:], []byte("-->"))
+			if end < 0 {
+				out.Write(src[i:])
+				return out.Bytes()
+			}
+			comment := src[i : i+4+end+3]
+			if bytes.HasPrefix(comment[4:], []byte("[if")) || bytes.HasPrefix(comment[4:], []byte("<![endif]")) {
+				out.Write(comment)
+			}
+			i += len(comment)
+		case src[i] == '<':
+			end := htmlTagEnd(src, i)
+			tag := src[i:end]
+			out.Write(tag)
+			i = end
+			if name := htmlRawTextElement(tag); name != "" {
+				closing := bytes.Index(bytes.ToLower(src[i:]), []byte("</"+name))
+				if closing < 0 {
+					out.Write(src[i:])
+					return out.Bytes()
+				}
+				out.Write(src[i : i+closing])
+				i += closing
+			}
+		case isHTMLSpace(src[i]):
+			for i < len(src) && isHTMLSpace(src[i]) {
+				i++
+			}
+			// A removed comment may leave two runs of whitespace next to each other
+			if out.Len() == 0 || out.Bytes()[out.Len()-1] != ' ' {
+				out.WriteByte(' ')
+			}
+		default:
+			out.WriteByte(src[i])
+			i++
+		}
+	}
+	return bytes.TrimSpace(out.Bytes())
+}
+
+// htmlTagEnd returns the index following the end of the tag starting at start, skipping
+// the quoted attribute values
+func htmlTagEnd(src []byte, start int) int {
+	var quote byte
+	for i := start + 1; i < len(src); i++ {
+		switch {
+		case quote != 0:
+			if src[i] == quote {
+				quote = 0
+			}
+		case src[i] == '"' || src[i] == '\'':
+			quote = src[i]
+		case src[i] == '>':
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// htmlRawTextElement returns the name of the element opened by the tag if its content must
+// be kept as is, or an empty string
+func htmlRawTextElement(tag []byte) string {
+	lower := strings.ToLower(string(tag))
+	for _, name := range htmlRawTextElements {
+		rest, ok := strings.CutPrefix(lower, "<"+name)
+		if ok && rest != "" && (isHTMLSpace(rest[0]) || rest[0] == '>' || rest[0] == '/') {
+			return name
+		}
+	}
+	return ""
+}
+
+// isHTMLSpace returns true for the HTML whitespace characters
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// minifierFor returns the minifier of the media type, or nil if it is not supported
+func minifierFor(mediaType string) func([]byte) []byte {
+	switch {
+	case mediaType == "text/html":
+		return minifyHTML
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return minifyJSON
+	default:
+		return nil
+	}
+}
+
+// MinifyMiddleware buffers the response and minifies its body when its Content-Type is one
+// of contentTypes (DefaultMinifyContentTypes if empty): whitespace is removed from JSON, and
+// comments and whitespace from HTML. Other types are sent unchanged. Responses that are
+// already encoded are not minified, so the middleware must be installed inside (after) a
+// compression middleware. Since the response is buffered, it cannot be streamed.
+func MinifyMiddleware(contentTypes []string) func(http.Handler) http.Handler {
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultMinifyContentTypes
+	}
+	types := make(map[string]bool, len(contentTypes))
+	for _, contentType := range contentTypes {
+		types[strings.ToLower(strings.TrimSpace(contentType))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buffered := newBufferedResponseWriter()
+			next.ServeHTTP(buffered, r)
+
+			header := buffered.Header()
+			header.Add("Vary", "Accept-Encoding")
+			mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+			if header.Get("Content-Encoding") == "" && types[mediaType] {
+				if minify := minifierFor(mediaType); minify != nil {
+					minified := minify(buffered.body.Bytes())
+					buffered.body.Reset()
+					buffered.body.Write(minified)
+					header.Del("Content-Length")
+				}
+			}
+			buffered.writeTo(w)
+		})
+	}
+}
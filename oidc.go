@@ -0,0 +1,345 @@
+package libserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Session keys used to carry the OIDC state and nonce between the login redirect and the callback
+const (
+	oidcStateSessionKey = "libserver.oidc.state"
+	oidcNonceSessionKey = "libserver.oidc.nonce"
+)
+
+// OIDCConfig configures the OpenID Connect authorization code flow
+type OIDCConfig struct {
+	// Issuer is the expected "iss" claim of the ID token
+	Issuer string
+	// ClientID is the OAuth2 client identifier, and the expected audience of the ID token
+	ClientID string
+	// ClientSecret is the OAuth2 client secret
+	ClientSecret string
+	// RedirectURL is the URL of the callback handler registered with the provider
+	RedirectURL string
+	// AuthURL is the provider's authorization endpoint
+	AuthURL string
+	// TokenURL is the provider's token endpoint
+	TokenURL string
+	// JWKSURL is the provider's JSON Web Key Set endpoint used to verify ID tokens
+	JWKSURL string
+	// Scopes are the requested scopes, "openid" is always included
+	Scopes []string
+	// SessionName is not used anymore, the session is found in the request context without name
+	//
+	// Deprecated: it can be left empty.
+	SessionName string
+	// HTTPClient is used to call the provider, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// IDToken is a verified OpenID Connect ID token
+type IDToken struct {
+	Subject      string
+	Issuer       string
+	Audience     []string
+	ExpiresAt    time.Time
+	IssuedAt     time.Time
+	Claims       jwt.MapClaims
+	Raw          string
+	AccessToken  string
+	RefreshToken string
+}
+
+// oidcTokenResponse is the token endpoint response
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+}
+
+// httpClient returns the HTTP client used to call the provider
+func (c OIDCConfig) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// AuthCodeURL returns the provider URL the user must be redirected to in order to log in.
+// The state and nonce are stored in the session and checked by the callback handler.
+func (c OIDCConfig) AuthCodeURL(session Session) (string, error) {
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := session.Set(oidcStateSessionKey, state); err != nil {
+		return "", err
+	}
+	if err := session.Set(oidcNonceSessionKey, nonce); err != nil {
+		return "", err
+	}
+
+	scopes := []string{"openid"}
+	for _, scope := range c.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURL},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	separator := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		separator = "&"
+	}
+	return c.AuthURL + separator + params.Encode(), nil
+}
+
+// NewOIDCCallbackHandler handles the authorization code callback of the OIDC flow started
+// with OIDCConfig.AuthCodeURL: it verifies the state, exchanges the code for tokens,
+// verifies the ID token and calls onSuccess with it so the application can set up its session.
+// Failures are answered with 400 (invalid callback) or 502 (provider error).
+func NewOIDCCallbackHandler(config OIDCConfig, onSuccess func(w http.ResponseWriter, r *http.Request, idToken IDToken)) http.HandlerFunc {
+	keys := &jwksCache{url: config.JWKSURL, client: config.httpClient(), mu: &sync.Mutex{}}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := GetSession(r.Context())
+		if session == nil {
+			http.Error(w, "no session", http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		if errCode := query.Get("error"); errCode != "" {
+			http.Error(w, "authorization failed: "+errCode, http.StatusBadRequest)
+			return
+		}
+
+		// Verify the state to protect against CSRF, it can only be used once
+		expectedState, _ := session.Get(oidcStateSessionKey).(string)
+		nonce, _ := session.Get(oidcNonceSessionKey).(string)
+		session.Delete(oidcStateSessionKey)
+		session.Delete(oidcNonceSessionKey)
+		state := query.Get("state")
+		if expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			http.Error(w, "missing authorization code", http.StatusBadRequest)
+			return
+		}
+		tokens, err := config.exchange(r, code)
+		if err != nil {
+			http.Error(w, "token exchange failed", http.StatusBadGateway)
+			return
+		}
+
+		idToken, err := config.verifyIDToken(tokens.IDToken, nonce, keys)
+		if err != nil {
+			http.Error(w, "invalid ID token", http.StatusBadGateway)
+			return
+		}
+		idToken.AccessToken = tokens.AccessToken
+		idToken.RefreshToken = tokens.RefreshToken
+		onSuccess(w, r, idToken)
+	}
+}
+
+// exchange exchanges the authorization code for tokens at the token endpoint
+func (c OIDCConfig) exchange(r *http.Request, code string) (*oidcTokenResponse, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.RedirectURL},
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	var tokens oidcTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+// verifyIDToken verifies the signature and the claims of a raw ID token
+func (c OIDCConfig) verifyIDToken(raw, nonce string, keys *jwksCache) (IDToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, keys.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(c.Issuer),
+		jwt.WithAudience(c.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return IDToken{}, err
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce != "" && tokenNonce != nonce {
+		return IDToken{}, errors.New("nonce mismatch")
+	}
+
+	idToken := IDToken{Claims: claims, Raw: raw}
+	idToken.Subject, _ = claims.GetSubject()
+	idToken.Issuer, _ = claims.GetIssuer()
+	idToken.Audience, _ = claims.GetAudience()
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		idToken.ExpiresAt = exp.Time
+	}
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		idToken.IssuedAt = iat.Time
+	}
+	return idToken, nil
+}
+
+// jwk is a JSON Web Key
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksCache caches the provider's signing keys, refreshing them when an unknown key is used
+type jwksCache struct {
+	url    string
+	client *http.Client
+	keys   map[string]any
+	mu     *sync.Mutex
+}
+
+// keyFunc returns the key verifying the token, as expected by jwt.Parse
+func (c *jwksCache) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// refresh downloads the key set
+func (c *jwksCache) refresh() error {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned %s", resp.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	c.keys = keys
+	return nil
+}
+
+// publicKey decodes the RSA or EC public key
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// randomToken returns a random URL-safe token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
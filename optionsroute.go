@@ -0,0 +1,99 @@
+package libserver
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// optionsRoute is the OPTIONS handler registered for the path of method-based patterns
+type optionsRoute struct {
+	allow   []string
+	handler http.Handler
+}
+
+// ServeHTTP calls the OPTIONS handler registered for the path, or answers with the allowed methods
+func (o *optionsRoute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if o.handler != nil {
+		o.handler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Allow", strings.Join(o.allow, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addOptionsRoute registers an OPTIONS handler for the path of a method-based pattern, e.g.
+// "OPTIONS /api/items" for "POST /api/items", so OPTIONS requests such as CORS preflight
+// requests get a session too. Nothing is registered if a route registered before already
+// matches OPTIONS requests for the path, e.g. "OPTIONS /{path...}" or a pattern without
+// method, so catch-all OPTIONS handlers must be registered first; registration failures
+// are logged.
+func (s *WebServer) addOptionsRoute(pattern string) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found || method == http.MethodOptions {
+		return
+	}
+	path = strings.TrimSpace(path)
+	if route, ok := s.optionsRoutes[path]; ok {
+		route.allowMethod(method)
+		return
+	}
+	if s.answersOptions(path) {
+		return
+	}
+	route := &optionsRoute{allow: []string{http.MethodOptions}}
+	route.allowMethod(method)
+	if err := handleOnMux(s.mux, http.MethodOptions+" "+path, s.wrapHandler(route.ServeHTTP)); err != nil {
+		s.logger().Warn("libserver: OPTIONS requests not answered automatically", "pattern", pattern, "error", err)
+		return
+	}
+	if s.optionsRoutes == nil {
+		s.optionsRoutes = make(map[string]*optionsRoute)
+	}
+	s.optionsRoutes[path] = route
+}
+
+// answersOptions returns true if a registered route matches the OPTIONS requests for the path
+// of a pattern, wildcards being matched by their own text
+func (s *WebServer) answersOptions(path string) bool {
+	host := ""
+	if i := strings.IndexByte(path, '/'); i > 0 {
+		host, path = path[:i], path[i:]
+	}
+	r := &http.Request{
+		Method: http.MethodOptions,
+		Host:   host,
+		URL:    &url.URL{Path: strings.TrimSuffix(path, "{$}")},
+	}
+	_, matched := s.mux.Handler(r)
+	return matched != ""
+}
+
+// allowMethod adds the method to the Allow header, GET implying HEAD like in http.ServeMux
+func (o *optionsRoute) allowMethod(method string) {
+	methods := []string{method}
+	if method == http.MethodGet {
+		methods = append(methods, http.MethodHead)
+	}
+	for _, m := range methods {
+		if !slices.Contains(o.allow, m) {
+			o.allow = append(o.allow, m)
+		}
+	}
+}
+
+// setOptionsHandler makes the handler of an OPTIONS pattern answer the OPTIONS requests of
+// a path already registered by addOptionsRoute, and returns false if there is none
+func (s *WebServer) setOptionsHandler(pattern string, handler http.Handler) bool {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found || method != http.MethodOptions {
+		return false
+	}
+	route, ok := s.optionsRoutes[strings.TrimSpace(path)]
+	if !ok || route.handler != nil {
+		return false
+	}
+	route.handler = handler
+	return true
+}
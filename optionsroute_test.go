@@ -0,0 +1,76 @@
+package libserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestServer returns a server with a default session manager, serving requests through its mux
+func newTestServer(t *testing.T) *WebServer {
+	t.Helper()
+	s := NewWebServer("test", "127.0.0.1", 0)
+	manager := NewDefaultSessionManager()
+	t.Cleanup(manager.Stop)
+	s.SetSessionManager(manager)
+	return s
+}
+
+// TestMethodPatternSessionInjection checks that a method-based route and its companion
+// OPTIONS route both get a session
+func TestMethodPatternSessionInjection(t *testing.T) {
+	s := newTestServer(t)
+	var postSession Session
+	s.AddHandlerFunc("POST /x", func(w http.ResponseWriter, r *http.Request) {
+		postSession = GetSession(r.Context())
+	})
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/x", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /x: status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if postSession == nil {
+		t.Fatal("POST /x: no session in the request context")
+	}
+
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/x", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS /x: status %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "OPTIONS, POST" {
+		t.Errorf("OPTIONS /x: Allow %q, want %q", allow, "OPTIONS, POST")
+	}
+	if len(rec.Result().Cookies()) == 0 {
+		t.Error("OPTIONS /x: no session cookie set")
+	}
+
+	// An OPTIONS handler registered afterwards replaces the automatic answer and gets a session
+	var optionsSession Session
+	s.AddHandlerFunc("OPTIONS /x", func(w http.ResponseWriter, r *http.Request) {
+		optionsSession = GetSession(r.Context())
+	})
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/x", nil))
+	if optionsSession == nil {
+		t.Fatal("OPTIONS /x handler: no session in the request context")
+	}
+}
+
+// TestOptionsRouteKeepsCatchAll checks that no OPTIONS route shadows an OPTIONS handler
+// registered before for the path
+func TestOptionsRouteKeepsCatchAll(t *testing.T) {
+	s := newTestServer(t)
+	calls := 0
+	s.AddHandlerFunc("OPTIONS /{path...}", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+	s.AddHandlerFunc("POST /api/items", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/api/items", nil))
+	if calls != 1 {
+		t.Fatalf("catch-all OPTIONS handler called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,26 @@
+package libserver
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ParseFormMiddleware parses the request form before the handler, so handlers get r.Form and
+// r.PostForm populated. Multipart bodies are parsed with ParseMultipartForm, keeping up to
+// maxMemory bytes of file parts in memory and the rest in temporary files; other bodies with
+// ParseForm. Malformed forms are answered with 400 Bad Request.
+func ParseFormMiddleware(maxMemory int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := r.ParseMultipartForm(maxMemory)
+			if errors.Is(err, http.ErrNotMultipart) {
+				err = r.ParseForm()
+			}
+			if err != nil {
+				http.Error(w, "invalid form: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package libserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// ErrMissingPathParam is wrapped by PathParamError when the request has no value for the parameter
+var ErrMissingPathParam = errors.New("missing path parameter")
+
+// PathParamError is returned when a path parameter is missing or cannot be parsed. It
+// always describes a client error, see StatusCode.
+type PathParamError struct {
+	// Name is the name of the parameter in the pattern
+	Name string
+	// Value is the raw value of the parameter
+	Value string
+	// Err is the parse error, or ErrMissingPathParam
+	Err error
+}
+
+// Error returns the error message
+func (e *PathParamError) Error() string {
+	if errors.Is(e.Err, ErrMissingPathParam) {
+		return fmt.Sprintf("missing path parameter %q", e.Name)
+	}
+	return fmt.Sprintf("invalid path parameter %q: %q: %v", e.Name, e.Value, e.Err)
+}
+
+// Unwrap returns the parse error
+func (e *PathParamError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns http.StatusBadRequest, the status to answer the request with
+func (e *PathParamError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// PathParam returns the path parameter name of the request (see http.Request.PathValue)
+// converted with parse. A missing or unparsable value is reported as a *PathParamError.
+func PathParam[T any](r *http.Request, name string, parse func(string) (T, error)) (T, error) {
+	value := r.PathValue(name)
+	if value == "" {
+		var zero T
+		return zero, &PathParamError{Name: name, Err: ErrMissingPathParam}
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		var zero T
+		return zero, &PathParamError{Name: name, Value: value, Err: err}
+	}
+	return parsed, nil
+}
+
+// PathParamInt returns the path parameter name of the request as a base 10 integer
+func PathParamInt(r *http.Request, name string) (int64, error) {
+	return PathParam(r, name, func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	})
+}
+
+// PathParamUUID returns the path parameter name of the request as a UUID
+func PathParamUUID(r *http.Request, name string) (uuid.UUID, error) {
+	return PathParam(r, name, uuid.Parse)
+}
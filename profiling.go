@@ -0,0 +1,152 @@
+package libserver
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultProfileSeconds is the duration of a CPU profile when the request gives none
+const DefaultProfileSeconds = 30
+
+// DefaultTraceSeconds is the duration of an execution trace when the request gives none
+const DefaultTraceSeconds = 1
+
+// registerProfiling registers the profiling handlers under prefix/pprof/ on the given mux
+func (s *WebServer) registerProfiling(mux *http.ServeMux, prefix string) error {
+	base := strings.TrimSuffix(prefix, "/") + "/pprof/"
+	handlers := []struct {
+		pattern string
+		handler http.HandlerFunc
+	}{
+		{base, profileIndex},
+		{base + "{profile}", serveProfile},
+		{base + "cmdline", serveCmdline},
+		{base + "profile", serveCPUProfile},
+		{base + "trace", serveTrace},
+	}
+	for _, h := range handlers {
+		if err := s.registerEndpoint(mux, h.pattern, h.handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnableProfiling serves the runtime profiles under prefix/pprof/ (e.g. prefix/pprof/heap) in
+// the format of net/http/pprof, so go tool pprof can read them, plus a CPU profile at
+// prefix/pprof/profile and an execution trace at prefix/pprof/trace. Nothing is registered on
+// http.DefaultServeMux. Profiling exposes internals of the process and is meant for
+// development or admin use only; prefer enabling it on a secondary listener, see AddListener
+// and RouteGroup.EnableProfiling. It returns ErrCustomRouter if the server uses a custom
+// router, see WithRouter, and the errors of Register if an endpoint conflicts with a route.
+func (s *WebServer) EnableProfiling(prefix string) error {
+	return s.registerProfiling(s.mux, prefix)
+}
+
+// EnableProfiling serves the runtime profiles under prefix/pprof/ on this route group,
+// typically the admin listener returned by AddListener. It returns the errors of
+// WebServer.EnableProfiling.
+func (g *RouteGroup) EnableProfiling(prefix string) error {
+	return g.server.registerProfiling(g.mux, prefix)
+}
+
+// profileIndex lists the available profiles
+func profileIndex(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/pprof/") {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	fmt.Fprint(w, "<html><head><title>profiles</title></head><body><ul>\n")
+	for _, profile := range pprof.Profiles() {
+		name := html.EscapeString(profile.Name())
+		fmt.Fprintf(w, "<li><a href=\"%s?debug=1\">%s</a> (%d)</li>\n", name, name, profile.Count())
+	}
+	fmt.Fprint(w, "<li><a href=\"profile\">profile</a> (CPU, ?seconds=N)</li>\n")
+	fmt.Fprint(w, "<li><a href=\"trace\">trace</a> (?seconds=N)</li>\n")
+	fmt.Fprint(w, "</ul></body></html>\n")
+}
+
+// serveProfile writes the named runtime profile, as text when the debug parameter is set
+func serveProfile(w http.ResponseWriter, r *http.Request) {
+	profile := pprof.Lookup(r.PathValue("profile"))
+	if profile == nil {
+		http.NotFound(w, r)
+		return
+	}
+	debug, _ := strconv.Atoi(r.FormValue("debug"))
+	if profile.Name() == "heap" && r.FormValue("gc") != "" {
+		runtime.GC()
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if debug != 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+profile.Name()+`"`)
+	}
+	profile.WriteTo(w, debug)
+}
+
+// serveCmdline writes the command line of the process, the arguments separated by NUL bytes
+func serveCmdline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	fmt.Fprint(w, strings.Join(os.Args, "\x00"))
+}
+
+// serveCPUProfile records a CPU profile for the seconds given in the request
+func serveCPUProfile(w http.ResponseWriter, r *http.Request) {
+	duration := profileDuration(r, DefaultProfileSeconds)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "cannot start CPU profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer pprof.StopCPUProfile()
+	waitProfile(r, duration)
+}
+
+// serveTrace records an execution trace for the seconds given in the request
+func serveTrace(w http.ResponseWriter, r *http.Request) {
+	duration := profileDuration(r, DefaultTraceSeconds)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "cannot start trace: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer trace.Stop()
+	waitProfile(r, duration)
+}
+
+// profileDuration returns the duration given by the seconds parameter of the request
+func profileDuration(r *http.Request, defaultSeconds int) time.Duration {
+	seconds, err := strconv.ParseFloat(r.FormValue("seconds"), 64)
+	if err != nil || seconds <= 0 {
+		seconds = float64(defaultSeconds)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// waitProfile waits for the duration of the profile, or until the client goes away
+func waitProfile(r *http.Request, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
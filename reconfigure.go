@@ -0,0 +1,76 @@
+package libserver
+
+import (
+	"net/http"
+	"slices"
+)
+
+// serverConfig is a snapshot of the settings Reconfigure may change
+type serverConfig struct {
+	sessionManager SessionManager
+	cookieName     string
+	tokenHeader    string
+	tenantResolver func(r *http.Request) string
+	corsConfig     *CORSConfig
+	corsHeaders    bool
+	middleware     []func(http.Handler) http.Handler
+	labels         []string
+	serverHeader   *string
+}
+
+// snapshotConfig returns the current reloadable settings
+func (s *WebServer) snapshotConfig() serverConfig {
+	return serverConfig{
+		sessionManager: s.sessionManager,
+		cookieName:     s.cookieName,
+		tokenHeader:    s.tokenHeader,
+		tenantResolver: s.tenantResolver,
+		corsConfig:     s.corsConfig,
+		corsHeaders:    s.corsHeaders,
+		middleware:     s.middleware,
+		labels:         s.middlewareLabels,
+		serverHeader:   s.serverHeader,
+	}
+}
+
+// restoreConfig applies a snapshot taken with snapshotConfig
+func (s *WebServer) restoreConfig(config serverConfig) {
+	s.SetSessionManager(config.sessionManager)
+	s.cookieName = config.cookieName
+	s.tokenHeader = config.tokenHeader
+	s.tenantResolver = config.tenantResolver
+	s.corsConfig = config.corsConfig
+	s.corsHeaders = config.corsHeaders
+	s.middleware = config.middleware
+	s.invalidateChains()
+	s.middlewareLabels = config.labels
+	s.serverHeader = config.serverHeader
+}
+
+// Reconfigure changes the configuration of a running server, e.g. after a configuration file
+// reload. fn is called with the server under the configuration lock and may call the setters
+// (SetSessionManager, SetSessionCookieName, SetCORSConfig, SetServerHeader, Use...); requests arriving while it
+// runs wait for the new configuration, while in-flight requests complete with the one they
+// started with. If fn returns an error, the previous settings are restored and the error is
+// returned. A replaced default session manager has its cleanup goroutine stopped.
+// fn must not call DestroySession, which would deadlock.
+func (s *WebServer) Reconfigure(fn func(ws *WebServer) error) error {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	previous := s.snapshotConfig()
+	// Copy the middleware so that Use in fn does not write into the previous slice
+	s.middleware = slices.Clone(s.middleware)
+	s.middlewareLabels = slices.Clone(s.middlewareLabels)
+	if err := fn(s); err != nil {
+		s.restoreConfig(previous)
+		return err
+	}
+
+	if previous.sessionManager != s.sessionManager {
+		if defaultManager, ok := previous.sessionManager.(*DefaultSessionManager); ok {
+			defaultManager.Stop()
+		}
+	}
+	return nil
+}
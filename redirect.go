@@ -0,0 +1,43 @@
+package libserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// expandWildcards replaces the {name} and {name...} wildcards of the target with the values
+// matched by the pattern of the request
+func expandWildcards(target string, r *http.Request) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(target, '{')
+		end := strings.IndexByte(target[start+1:], '}')
+		if start < 0 || end < 0 {
+			b.WriteString(target)
+			return b.String()
+		}
+		end += start + 1
+		b.WriteString(target[:start])
+		b.WriteString(r.PathValue(strings.TrimSuffix(target[start+1:end], "...")))
+		target = target[end+1:]
+	}
+}
+
+// AddRedirect registers a redirect from the from pattern, which may use the full ServeMux
+// syntax with methods and wildcards, to the to URL with the given 3xx status code. The
+// wildcards of from can be used in to, e.g. "/posts/{id}" to "/articles/{id}", and the query
+// string is kept when to has none. An error is returned for an invalid status code, and the
+// errors of Register for an invalid pattern or a pattern conflicting with a registered route.
+func (s *WebServer) AddRedirect(from, to string, statusCode int) error {
+	if statusCode < 300 || statusCode > 399 {
+		return fmt.Errorf("invalid redirect status code %d", statusCode)
+	}
+	return s.Register(from, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := expandWildcards(to, r)
+		if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, statusCode)
+	}))
+}
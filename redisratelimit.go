@@ -0,0 +1,61 @@
+package libserver
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRateLimitKeyPrefix is the prefix of the Redis keys holding the request counters
+const redisRateLimitKeyPrefix = "libserver:ratelimit:"
+
+// redisRateLimitScript increments the counter of the current window and sets its
+// expiration on the first request, atomically and in a single round trip
+var redisRateLimitScript = redis.NewScript(`
+local current = redis.call("INCR", KEYS[1])
+if current == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {current, redis.call("PTTL", KEYS[1])}
+`)
+
+// Rate is a number of requests allowed per period
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// RedisRateLimiter limits requests across server instances using counters stored in Redis.
+// Requests are grouped by the key returned by keyFn (e.g. the client IP or user ID).
+// Requests over the limit are rejected with 429 Too Many Requests. If Redis is
+// unavailable, the error is logged and the request is let through.
+func RedisRateLimiter(client *redis.Client, keyFn func(*http.Request) string, limit Rate) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := redisRateLimitKeyPrefix + keyFn(r)
+			result, err := redisRateLimitScript.Run(r.Context(), client, []string{key}, limit.Period.Milliseconds()).Int64Slice()
+			if err != nil || len(result) != 2 {
+				slog.ErrorContext(r.Context(), "libserver: rate limiter unavailable", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			count, ttl := result[0], time.Duration(result[1])*time.Millisecond
+			remaining := max(int64(limit.Limit)-count, 0)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+
+			if count > int64(limit.Limit) {
+				retryAfter := int(math.Ceil(ttl.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
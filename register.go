@@ -0,0 +1,99 @@
+package libserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrCustomRouter is returned when registering a route on a server using a custom router
+var ErrCustomRouter = errors.New("routes must be registered on the custom router set with WithRouter")
+
+// RouteConflictError is returned when a pattern matches the same requests as a registered one
+type RouteConflictError struct {
+	// Pattern is the pattern being registered
+	Pattern string
+	// Existing is the registered pattern it conflicts with
+	Existing string
+}
+
+// Error returns the error message
+func (e *RouteConflictError) Error() string {
+	return fmt.Sprintf("pattern %q conflicts with registered pattern %q", e.Pattern, e.Existing)
+}
+
+// InvalidPatternError is returned when a pattern cannot be parsed or the handler is nil
+type InvalidPatternError struct {
+	// Pattern is the invalid pattern
+	Pattern string
+	// Reason is the error reported by http.ServeMux
+	Reason string
+}
+
+// Error returns the error message
+func (e *InvalidPatternError) Error() string {
+	return fmt.Sprintf("invalid pattern %q: %s", e.Pattern, e.Reason)
+}
+
+// handleOnMux registers the handler on the mux, turning the ServeMux panics into errors
+func handleOnMux(mux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		message := fmt.Sprint(p)
+		// e.g. pattern "GET /a" (registered at ...) conflicts with pattern "/a" (registered at ...)
+		if _, rest, found := strings.Cut(message, "conflicts with pattern "); found {
+			existing := rest
+			if end := strings.Index(rest, " (registered at"); end >= 0 {
+				existing = rest[:end]
+			}
+			err = &RouteConflictError{Pattern: pattern, Existing: strings.Trim(existing, `"`)}
+			return
+		}
+		err = &InvalidPatternError{Pattern: pattern, Reason: message}
+	}()
+	mux.Handle(pattern, handler)
+	return nil
+}
+
+// registerEndpoint registers a built-in endpoint, which creates no session and is not
+// counted in the stats, on the given mux. It returns ErrCustomRouter if the mux is the one
+// of a server using a custom router, since the endpoint would never be served, and the
+// errors of Register for invalid or conflicting patterns.
+func (s *WebServer) registerEndpoint(mux *http.ServeMux, pattern string, handler http.Handler) error {
+	if mux == s.mux && s.router != nil {
+		return ErrCustomRouter
+	}
+	if err := handleOnMux(mux, pattern, handler); err != nil {
+		return err
+	}
+	s.logRoute(pattern)
+	return nil
+}
+
+// Register adds a handler for the given pattern like AddHandler, but returns an error instead
+// of panicking: a *RouteConflictError if the pattern conflicts with a registered route, an
+// *InvalidPatternError if it is invalid, or ErrCustomRouter if the server uses a custom router.
+func (s *WebServer) Register(pattern string, handler http.Handler) error {
+	if s.router != nil {
+		return ErrCustomRouter
+	}
+	if handler == nil {
+		return &InvalidPatternError{Pattern: pattern, Reason: "nil handler"}
+	}
+	if s.setOptionsHandler(pattern, handler) {
+		s.routes = append(s.routes, pattern)
+		s.logRoute(pattern)
+		return nil
+	}
+	if err := handleOnMux(s.mux, pattern, s.wrapHandler(handler.ServeHTTP)); err != nil {
+		return err
+	}
+	s.routes = append(s.routes, pattern)
+	s.logRoute(pattern)
+	s.addOptionsRoute(pattern)
+	return nil
+}
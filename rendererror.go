@@ -0,0 +1,86 @@
+package libserver
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorTemplateName is the template rendered by RenderError for HTML clients
+const ErrorTemplateName = "error.html"
+
+// ErrorPage is the data of the error template
+type ErrorPage struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// StatusText is the text of the status code, e.g. "Not Found"
+	StatusText string
+	// Message is the error message
+	Message string
+}
+
+// defaultErrorTemplate is rendered when the template renderer has no error template
+var defaultErrorTemplate = template.Must(template.New(ErrorTemplateName).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.StatusCode}} {{.StatusText}}</title></head>
+<body>
+<h1>{{.StatusCode}} {{.StatusText}}</h1>
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+// SetTemplateRenderer sets the template renderer used by RenderError
+func (s *WebServer) SetTemplateRenderer(renderer *TemplateRenderer) {
+	s.templates = renderer
+}
+
+// RenderError writes an error response in the format the client asks for: clients accepting
+// HTML rather than JSON, such as browsers, get the error.html template of the renderer set
+// with SetTemplateRenderer, executed with an ErrorPage, or a minimal built-in page when there
+// is no such template. Other clients get a {"error": message} JSON body.
+func (s *WebServer) RenderError(w http.ResponseWriter, r *http.Request, statusCode int, message string) error {
+	if !prefersHTML(r) {
+		return WriteJSON(w, statusCode, map[string]string{"error": message})
+	}
+	page := ErrorPage{StatusCode: statusCode, StatusText: http.StatusText(statusCode), Message: message}
+	if s.templates != nil {
+		err := s.templates.renderStatus(w, statusCode, ErrorTemplateName, page)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return writeTemplate(w, statusCode, defaultErrorTemplate, page)
+}
+
+// mediaQuality returns the quality factor the Accept header gives explicitly to the media
+// type, without considering wildcards, or 0 if it is not listed
+func mediaQuality(accept, mediaType string) float64 {
+	best := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(mediaRange), mediaType) {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		best = max(best, quality)
+	}
+	return best
+}
+
+// prefersHTML returns true if the client accepts HTML with a higher quality than JSON
+func prefersHTML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	html := max(mediaQuality(accept, "text/html"), mediaQuality(accept, "application/xhtml+xml"))
+	return html > mediaQuality(accept, "application/json")
+}
@@ -0,0 +1,123 @@
+package libserver
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps an http.ResponseWriter to capture the status code and bytes written
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+	wroteHeader  bool
+	flushEvery   int64
+	unflushed    int64
+	contentType  string
+}
+
+// newResponseWriter wraps the given response writer
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+}
+
+// WriteHeader captures the status code and sends the response header
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.statusCode = statusCode
+	w.wroteHeader = true
+	if w.contentType != "" && statusCode >= http.StatusOK && statusCode != http.StatusNoContent &&
+		statusCode != http.StatusNotModified && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", w.contentType)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the data and counts the bytes written
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytesWritten += int64(n)
+	if w.flushEvery > 0 {
+		w.unflushed += int64(n)
+		if w.unflushed >= w.flushEvery {
+			w.Flush()
+		}
+	}
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the underlying writer supports it
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+		w.unflushed = 0
+	}
+}
+
+// Hijack lets the handler take over the connection (e.g. for WebSocket upgrades)
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap returns the underlying response writer, for use by http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bufferedResponseWriter records a response in memory so it can be inspected,
+// modified or discarded before being sent to the client
+type bufferedResponseWriter struct {
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+}
+
+// newBufferedResponseWriter creates an empty buffered response writer
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{
+		header:     make(http.Header),
+		statusCode: http.StatusOK,
+	}
+}
+
+// Header returns the buffered response header
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status code
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+// Write appends the data to the buffered body
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// writeTo sends the buffered header, status code and body to the given response writer
+func (w *bufferedResponseWriter) writeTo(dst http.ResponseWriter) error {
+	header := dst.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+	dst.WriteHeader(w.statusCode)
+	_, err := dst.Write(w.body.Bytes())
+	return err
+}
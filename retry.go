@@ -0,0 +1,109 @@
+package libserver
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultRetryMaxAttempts is the default number of attempts made by the retry middleware
+	DefaultRetryMaxAttempts = 3
+	// DefaultRetryInitialBackoff is the default delay before the first retry
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	// DefaultRetryMaxBackoff is the default upper bound of the delay between retries
+	DefaultRetryMaxBackoff = 2 * time.Second
+)
+
+// RetryConfig configures the retry middleware. Zero values are replaced by defaults.
+type RetryConfig struct {
+	// MaxAttempts is the total number of times the handler may be invoked
+	MaxAttempts int
+	// InitialBackoff is the base delay, doubled after each attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts
+	MaxBackoff time.Duration
+	// RetryStatusCodes are the status codes triggering a retry (default 502 and 503)
+	RetryStatusCodes []int
+	// RetryMethods are the methods that may be retried (default GET, HEAD and OPTIONS)
+	RetryMethods []string
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxAttempts < 1 {
+		c.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if c.RetryStatusCodes == nil {
+		c.RetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable}
+	}
+	if c.RetryMethods == nil {
+		c.RetryMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+	return c
+}
+
+// backoff returns the delay before the given retry using exponential backoff with full jitter
+func (c RetryConfig) backoff(retry int) time.Duration {
+	delay := c.InitialBackoff << retry
+	if delay <= 0 || delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	return rand.N(delay) + 1
+}
+
+// RetryMiddleware re-invokes the handler when it responds with a retryable status code.
+// Responses are buffered until the final attempt, and only the configured methods are retried.
+func RetryMiddleware(config RetryConfig) func(http.Handler) http.Handler {
+	config = config.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(config.RetryMethods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Keep the request body so it can be replayed on each attempt
+			var body []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+			}
+
+			for attempt := 1; ; attempt++ {
+				if body != nil {
+					r.Body = io.NopCloser(bytes.NewReader(body))
+				}
+				rw := newBufferedResponseWriter()
+				next.ServeHTTP(rw, r)
+				if attempt >= config.MaxAttempts || !slices.Contains(config.RetryStatusCodes, rw.statusCode) {
+					rw.writeTo(w)
+					return
+				}
+
+				timer := time.NewTimer(config.backoff(attempt - 1))
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					rw.writeTo(w)
+					return
+				}
+			}
+		})
+	}
+}
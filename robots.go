@@ -0,0 +1,95 @@
+package libserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RobotsTxtMaxAge is the number of seconds clients may cache the robots.txt served by ServeRobotsTxt
+const RobotsTxtMaxAge = 24 * 60 * 60
+
+// ServeRobotsTxt serves rules at /robots.txt as text/plain, cacheable for a day (see
+// RobotsTxtMaxAge) and supporting conditional requests. Requests for it do not create
+// sessions. RobotsTxtBuilder builds the rules. It returns ErrCustomRouter if the server
+// uses a custom router, see WithRouter, and a *RouteConflictError if /robots.txt is
+// already registered.
+func (s *WebServer) ServeRobotsTxt(rules string) error {
+	modTime := time.Now()
+	return s.registerEndpoint(s.mux, "GET /robots.txt", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(RobotsTxtMaxAge))
+		http.ServeContent(w, r, "robots.txt", modTime, strings.NewReader(rules))
+	}))
+}
+
+// robotsTxtRule is an Allow or Disallow line of a robots.txt group
+type robotsTxtRule struct {
+	directive string
+	path      string
+}
+
+// RobotsTxtBuilder builds the rules of a robots.txt file, grouping them by user agent in the
+// order the user agents first appear, e.g.
+//
+//	rules := NewRobotsTxtBuilder().Disallow("*", "/admin/").Sitemap("https://example.com/sitemap.xml").String()
+type RobotsTxtBuilder struct {
+	userAgents []string
+	rules      map[string][]robotsTxtRule
+	sitemaps   []string
+}
+
+// NewRobotsTxtBuilder creates an empty robots.txt builder
+func NewRobotsTxtBuilder() *RobotsTxtBuilder {
+	return &RobotsTxtBuilder{
+		rules: make(map[string][]robotsTxtRule),
+	}
+}
+
+// Disallow forbids userAgent ("*" for every crawler) to crawl the paths starting with path
+func (b *RobotsTxtBuilder) Disallow(userAgent, path string) *RobotsTxtBuilder {
+	return b.add(userAgent, "Disallow", path)
+}
+
+// Allow lets userAgent ("*" for every crawler) crawl the paths starting with path, e.g. to
+// make an exception to a broader Disallow rule
+func (b *RobotsTxtBuilder) Allow(userAgent, path string) *RobotsTxtBuilder {
+	return b.add(userAgent, "Allow", path)
+}
+
+// Sitemap adds the absolute URL of a sitemap
+func (b *RobotsTxtBuilder) Sitemap(url string) *RobotsTxtBuilder {
+	b.sitemaps = append(b.sitemaps, url)
+	return b
+}
+
+// add adds a rule to the group of the user agent
+func (b *RobotsTxtBuilder) add(userAgent, directive, path string) *RobotsTxtBuilder {
+	if _, ok := b.rules[userAgent]; !ok {
+		b.userAgents = append(b.userAgents, userAgent)
+	}
+	b.rules[userAgent] = append(b.rules[userAgent], robotsTxtRule{directive: directive, path: path})
+	return b
+}
+
+// String returns the robots.txt rules
+func (b *RobotsTxtBuilder) String() string {
+	var sb strings.Builder
+	for i, userAgent := range b.userAgents {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("User-agent: " + userAgent + "\n")
+		for _, rule := range b.rules[userAgent] {
+			sb.WriteString(rule.directive + ": " + rule.path + "\n")
+		}
+	}
+	if len(b.sitemaps) > 0 && len(b.userAgents) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, sitemap := range b.sitemaps {
+		sb.WriteString("Sitemap: " + sitemap + "\n")
+	}
+	return sb.String()
+}
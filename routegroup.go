@@ -0,0 +1,141 @@
+package libserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RouteGroup is a set of routes sharing the server's session manager and server data,
+// and optionally a path prefix, middleware and its own session manager
+type RouteGroup struct {
+	id             int
+	server         *WebServer
+	mux            *http.ServeMux
+	prefix         string
+	middleware     []func(http.Handler) http.Handler
+	sessionManager SessionManager
+}
+
+// sessionScope is the session manager of a request and the path and name suffix of its
+// session cookie
+type sessionScope struct {
+	manager      SessionManager
+	cookiePath   string
+	cookieSuffix string
+}
+
+// sessionScopeContextKey is the context key of the session scope of the request
+type sessionScopeContextKey struct{}
+
+// sessionCookie returns the session cookie with the given name and value, scoped to the path
+func (sc sessionScope) sessionCookie(s *WebServer, name, value string) *http.Cookie {
+	cookie := s.sessionCookie(name, value)
+	cookie.Path = sc.cookiePath
+	return cookie
+}
+
+// cookieName returns the name of the session cookie of the scope for the tenant
+func (sc sessionScope) cookieName(s *WebServer, tenant string) string {
+	return s.tenantCookieName(tenant) + sc.cookieSuffix
+}
+
+// groupSessionScope returns the session scope of the handlers of the group, the server
+// session manager being used when the group is nil or has no session manager
+func (s *WebServer) groupSessionScope(group *RouteGroup) sessionScope {
+	if group == nil || group.sessionManager == nil {
+		return sessionScope{manager: s.sessionManager, cookiePath: "/"}
+	}
+	cookiePath := group.prefix
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	return sessionScope{
+		manager:      group.sessionManager,
+		cookiePath:   cookiePath,
+		cookieSuffix: "." + strconv.Itoa(group.id),
+	}
+}
+
+// requestSessionScope returns the session scope of the request, the server one when the
+// request was not served by a wrapped handler
+func (s *WebServer) requestSessionScope(r *http.Request) sessionScope {
+	if scope, ok := r.Context().Value(sessionScopeContextKey{}).(sessionScope); ok {
+		return scope
+	}
+	return s.groupSessionScope(nil)
+}
+
+// newRouteGroup creates a new route group registering its routes on the given mux
+func newRouteGroup(server *WebServer, mux *http.ServeMux) *RouteGroup {
+	server.groupCount++
+	return &RouteGroup{
+		id:     server.groupCount,
+		server: server,
+		mux:    mux,
+	}
+}
+
+// AddGroup creates a route group on the server whose patterns are prefixed with prefix,
+// e.g. "GET /users" registered on the "/admin" group serves "GET /admin/users"
+func (s *WebServer) AddGroup(prefix string) *RouteGroup {
+	group := newRouteGroup(s, s.mux)
+	group.prefix = strings.TrimSuffix(prefix, "/")
+	return group
+}
+
+// AddGroupWithAuth creates a route group on the server under prefix whose handlers all pass
+// through the given authentication middleware, the first one being the outermost
+func (s *WebServer) AddGroupWithAuth(prefix string, auth ...func(http.Handler) http.Handler) *RouteGroup {
+	return s.AddGroup(prefix).Use(auth...)
+}
+
+// Use adds middleware applied to the handlers registered afterwards on the group, inside the
+// global middleware. The first one registered is the outermost.
+func (g *RouteGroup) Use(middleware ...func(http.Handler) http.Handler) *RouteGroup {
+	g.middleware = append(g.middleware, middleware...)
+	return g
+}
+
+// SetSessionManager makes the handlers of the group create and retrieve their sessions with
+// manager instead of the server session manager, e.g. to keep the sessions of authenticated
+// routes in Redis and the anonymous ones in memory. Server data is still the server one.
+// The session cookie of the group is named after the server one followed by "." and the
+// number of the group in creation order (e.g. "myapp.2"), and scoped to the group prefix, so
+// that it never replaces the server one, including for groups without prefix.
+func (g *RouteGroup) SetSessionManager(manager SessionManager) {
+	g.server.configMu.Lock()
+	defer g.server.configMu.Unlock()
+	g.sessionManager = manager
+}
+
+// AddHandlerFunc adds a handler function for the given pattern
+func (g *RouteGroup) AddHandlerFunc(pattern string, handler http.HandlerFunc) {
+	g.AddHandler(pattern, handler)
+}
+
+// AddHandler adds a handler for the given pattern
+func (g *RouteGroup) AddHandler(pattern string, handler http.Handler) {
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		handler = g.middleware[i](handler)
+	}
+	pattern = g.prefixPattern(pattern)
+	if g.mux == g.server.mux {
+		g.server.checkNoRouter()
+	}
+	g.server.routes = append(g.server.routes, pattern)
+	g.mux.HandleFunc(pattern, g.server.wrapGroupHandler(handler.ServeHTTP, g))
+	g.server.logRoute(pattern)
+}
+
+// prefixPattern prefixes the path of the pattern, keeping its method
+func (g *RouteGroup) prefixPattern(pattern string) string {
+	if g.prefix == "" {
+		return pattern
+	}
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		return g.prefix + pattern
+	}
+	return method + " " + g.prefix + strings.TrimSpace(path)
+}
@@ -1,19 +1,32 @@
 package libserver
 
-import "sync"
+import (
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// dataWatcher is a callback registered with Watch
+type dataWatcher struct {
+	id int
+	fn func(old, new any)
+}
 
 // ServerData is a thread-safe key-value store for global application data
 type ServerData struct {
 	data           map[string]any
 	sessionManager SessionManager
+	watchers       map[string][]dataWatcher
+	nextWatcherID  int
 	mu             *sync.RWMutex
 }
 
 // NewServerData creates a new ServerData instance
 func NewServerData() *ServerData {
 	return &ServerData{
-		data: make(map[string]any),
-		mu:   &sync.RWMutex{},
+		data:     make(map[string]any),
+		watchers: make(map[string][]dataWatcher),
+		mu:       &sync.RWMutex{},
 	}
 }
 
@@ -21,7 +34,9 @@ func NewServerData() *ServerData {
 func (s *ServerData) Set(key string, value any) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	old := s.data[key]
 	s.data[key] = value
+	s.notify(key, old, value)
 }
 
 // Get retrieves a value by its key, returns nil if not found
@@ -31,11 +46,43 @@ func (s *ServerData) Get(key string) any {
 	return s.data[key]
 }
 
+// GetOrSet returns the value of key, computing and storing it with fn if the key is absent.
+// fn is called under the write lock, so it runs at most once per key even when concurrent
+// requests miss the key at the same time, which prevents the thundering herd on expensive
+// computations. On error, nothing is stored and the error is returned, so the next call
+// calls fn again. fn must not call ServerData methods.
+func (s *ServerData) GetOrSet(key string, fn func() (any, error)) (any, error) {
+	s.mu.RLock()
+	value, ok := s.data[key]
+	s.mu.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another caller may have stored the value while the lock was released
+	if value, ok := s.data[key]; ok {
+		return value, nil
+	}
+	value, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	s.data[key] = value
+	s.notify(key, nil, value)
+	return value, nil
+}
+
 // Delete removes a value by its key
 func (s *ServerData) Delete(key string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	old, ok := s.data[key]
 	delete(s.data, key)
+	if ok {
+		s.notify(key, old, nil)
+	}
 }
 
 // Has checks if a key exists
@@ -50,7 +97,13 @@ func (s *ServerData) Has(key string) bool {
 func (s *ServerData) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	old := s.data
 	s.data = make(map[string]any)
+	for key := range s.watchers {
+		if value, ok := old[key]; ok {
+			s.notify(key, value, nil)
+		}
+	}
 }
 
 // SetSessionManager sets the session manager
@@ -84,3 +137,102 @@ func (s *ServerData) Len() int {
 	defer s.mu.RUnlock()
 	return len(s.data)
 }
+
+// Watch registers a callback invoked whenever Set, Delete, Clear, Update or GetOrSet changes
+// the value of key, and returns a function unregistering it. Callbacks are called
+// synchronously while the write lock is held, so they must not call ServerData methods.
+// A deleted value is reported as nil.
+func (s *ServerData) Watch(key string, fn func(old, new any)) (unwatch func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextWatcherID
+	s.nextWatcherID++
+	s.watchers[key] = append(s.watchers[key], dataWatcher{id: id, fn: fn})
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.removeWatcher(key, func(watcher dataWatcher) bool {
+			return watcher.id == id
+		})
+	}
+}
+
+// Unwatch removes a callback previously registered with Watch for key. Functions are compared
+// by code pointer, so closures created by the same function literal cannot be told apart and
+// the first one registered is removed.
+//
+// Deprecated: call the function returned by Watch, which removes exactly the callback it
+// registered.
+func (s *ServerData) Unwatch(key string, fn func(old, new any)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target := reflect.ValueOf(fn).Pointer()
+	removed := false
+	s.removeWatcher(key, func(watcher dataWatcher) bool {
+		if removed || reflect.ValueOf(watcher.fn).Pointer() != target {
+			return false
+		}
+		removed = true
+		return true
+	})
+}
+
+// removeWatcher removes the watchers of key matching the function, the write lock must be held
+func (s *ServerData) removeWatcher(key string, match func(watcher dataWatcher) bool) {
+	watchers := slices.DeleteFunc(s.watchers[key], match)
+	if len(watchers) == 0 {
+		delete(s.watchers, key)
+	} else {
+		s.watchers[key] = watchers
+	}
+}
+
+// notify calls the watchers of key, the write lock must be held
+func (s *ServerData) notify(key string, old, new any) {
+	for _, watcher := range s.watchers[key] {
+		watcher.fn(old, new)
+	}
+}
+
+// Update calls fn with the underlying map under the write lock, so several keys can be read
+// and changed atomically (e.g. swapping two values). fn must not keep the map nor call
+// ServerData methods. Watchers of the keys whose value changed are notified afterwards.
+func (s *ServerData) Update(fn func(data map[string]any)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watched := make(map[string]any, len(s.watchers))
+	for key := range s.watchers {
+		watched[key] = s.data[key]
+	}
+	fn(s.data)
+	for key, old := range watched {
+		if value := s.data[key]; !sameValue(old, value) {
+			s.notify(key, old, value)
+		}
+	}
+}
+
+// View calls fn with the underlying map under the read lock, so several keys can be read
+// consistently. fn must not modify nor keep the map, nor call ServerData methods.
+func (s *ServerData) View(fn func(data map[string]any)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.data)
+}
+
+// sameValue returns true if both values are equal, values of uncomparable types such as
+// slices or maps are always reported as different
+func sameValue(a, b any) (same bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	// Structs holding uncomparable values in interface fields panic when compared
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	typ := reflect.TypeOf(a)
+	return typ == reflect.TypeOf(b) && typ.Comparable() && a == b
+}
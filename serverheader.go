@@ -0,0 +1,89 @@
+package libserver
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// SetServerHeader sets the Server header of every response, e.g. "myapp/1.2.3", unless the
+// handler sets its own. net/http sends no Server header by default. An empty value removes
+// the header from every response, including the ones set by handlers or copied from
+// upstream responses by reverse proxies. To change it while the server is running, call it
+// from Reconfigure.
+func (s *WebServer) SetServerHeader(value string) {
+	s.serverHeader = &value
+}
+
+// withServerHeader wraps the handler to set or remove the Server header, reading the setting
+// on every request so that it can change after Start
+func (s *WebServer) withServerHeader(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.configMu.RLock()
+		serverHeader := s.serverHeader
+		s.configMu.RUnlock()
+		if serverHeader == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if value := *serverHeader; value != "" {
+			// Set before the handler runs, so the handler can replace it
+			w.Header().Set("Server", value)
+			handler.ServeHTTP(w, r)
+			return
+		}
+		sw := &serverHeaderWriter{ResponseWriter: w}
+		handler.ServeHTTP(sw, r)
+		if !sw.wroteHeader {
+			// net/http sends the header of responses without body after the handler returns
+			w.Header().Del("Server")
+		}
+	})
+}
+
+// serverHeaderWriter removes the Server header before the response header is sent
+type serverHeaderWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader removes the Server header and sends the response header
+func (w *serverHeaderWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.Header().Del("Server")
+		// Informational responses are followed by the final one
+		w.wroteHeader = statusCode >= http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the data, removing the Server header first if the header is not sent yet
+func (w *serverHeaderWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client if the underlying writer supports it
+func (w *serverHeaderWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection (e.g. for WebSocket upgrades)
+func (w *serverHeaderWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap returns the underlying response writer, for use by http.ResponseController
+func (w *serverHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,127 @@
+package libserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerTimerKey is the context key for accessing the ServerTimer
+const ServerTimerKey ContextKey = "serverTimer"
+
+// serverTimingMetric is a named phase measured by a ServerTimer
+type serverTimingMetric struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	ended    bool
+}
+
+// ServerTimer records the duration of named request phases for the Server-Timing header
+type ServerTimer struct {
+	metrics []*serverTimingMetric
+	mu      *sync.Mutex
+}
+
+// NewServerTimer creates an empty server timer
+func NewServerTimer() *ServerTimer {
+	return &ServerTimer{mu: &sync.Mutex{}}
+}
+
+// Mark starts measuring the named phase. It is a no-op on a nil timer, so handlers can
+// use the timer from the context whether or not the middleware is installed.
+func (t *ServerTimer) Mark(name string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = append(t.metrics, &serverTimingMetric{name: name, start: time.Now()})
+}
+
+// MarkEnd stops measuring the named phase started with Mark
+func (t *ServerTimer) MarkEnd(name string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.metrics) - 1; i >= 0; i-- {
+		if metric := t.metrics[i]; metric.name == name && !metric.ended {
+			metric.duration = time.Since(metric.start)
+			metric.ended = true
+			return
+		}
+	}
+}
+
+// header formats the ended phases as a Server-Timing header value
+func (t *ServerTimer) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := make([]string, 0, len(t.metrics))
+	for _, metric := range t.metrics {
+		if metric.ended {
+			parts = append(parts, fmt.Sprintf("%s;dur=%.1f", metric.name, float64(metric.duration.Microseconds())/1000))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// serverTimingWriter adds the Server-Timing header right before the response header is sent
+type serverTimingWriter struct {
+	http.ResponseWriter
+	timer       *ServerTimer
+	wroteHeader bool
+}
+
+// WriteHeader adds the Server-Timing header and sends the response header
+func (w *serverTimingWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if value := w.timer.header(); value != "" {
+			w.Header().Add("Server-Timing", value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write sends the response header if needed and writes the data
+func (w *serverTimingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying response writer, for use by http.ResponseController
+func (w *serverTimingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ServerTimingMiddleware injects a ServerTimer into the request context and writes the
+// phases measured by the handler as a Server-Timing header. Since headers cannot change
+// once the body is being written, only the phases ended before the first write are reported.
+func ServerTimingMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timer := NewServerTimer()
+			tw := &serverTimingWriter{ResponseWriter: w, timer: timer}
+			next.ServeHTTP(tw, r.WithContext(context.WithValue(r.Context(), ServerTimerKey, timer)))
+			if !tw.wroteHeader {
+				tw.WriteHeader(http.StatusOK)
+			}
+		})
+	}
+}
+
+// GetServerTimerFromContext retrieves the ServerTimer from a request context
+func GetServerTimerFromContext(ctx context.Context) *ServerTimer {
+	if timer, ok := ctx.Value(ServerTimerKey).(*ServerTimer); ok {
+		return timer
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package libserver
 type Session interface {
 	Id() string
 	Get(key string) any
-	Set(key string, value any)
+	Set(key string, value any) error
 	Delete(key string)
 	Has(key string) bool
 	IsExpired() bool
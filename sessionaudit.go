@@ -0,0 +1,55 @@
+package libserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// Session audit event types
+const (
+	// SessionEventCreated is emitted when a new session is created for a request
+	SessionEventCreated = "created"
+	// SessionEventExpired is emitted when a request presents an expired session
+	SessionEventExpired = "expired"
+	// SessionEventDeleted is emitted when a session is destroyed with DestroySession
+	SessionEventDeleted = "deleted"
+	// SessionEventAccessed is emitted for every request using a session, when enabled
+	SessionEventAccessed = "accessed"
+	// SessionEventIPChanged is emitted when a session is deleted because the client IP changed
+	SessionEventIPChanged = "ip_changed"
+)
+
+// SessionAuditEvent describes a session lifecycle event for audit logging
+type SessionAuditEvent struct {
+	EventType  string
+	SessionID  string
+	RemoteAddr string
+	UserAgent  string
+	Timestamp  time.Time
+}
+
+// SetSessionAuditLogger registers a callback invoked on session creation, expiry and deletion
+func (s *WebServer) SetSessionAuditLogger(fn func(event SessionAuditEvent)) {
+	s.auditLogger = fn
+}
+
+// SetSessionAuditAccess enables or disables audit events for every request accessing a session
+func (s *WebServer) SetSessionAuditAccess(enabled bool) {
+	s.auditAccess = enabled
+}
+
+// auditSession logs a session event for the given request and sends it to the audit logger, if any
+func (s *WebServer) auditSession(eventType, sessionID string, r *http.Request) {
+	// The session ID is a credential, only the event is logged
+	s.logger().DebugContext(r.Context(), "libserver: session "+eventType, "remoteAddr", r.RemoteAddr)
+	if s.auditLogger == nil {
+		return
+	}
+	s.auditLogger(SessionAuditEvent{
+		EventType:  eventType,
+		SessionID:  sessionID,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+		Timestamp:  time.Now(),
+	})
+}
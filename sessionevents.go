@@ -0,0 +1,114 @@
+package libserver
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionEventType is the type of a session lifecycle event published on a SessionEventBus
+type SessionEventType string
+
+// Session lifecycle event types
+const (
+	// SessionCreated is published when the session manager creates a session
+	SessionCreated SessionEventType = "created"
+	// SessionExpired is published when the cleanup removes an expired session
+	SessionExpired SessionEventType = "expired"
+	// SessionDeleted is published when a session is deleted, e.g. on logout
+	SessionDeleted SessionEventType = "deleted"
+	// SessionUpdated is published when a session is renewed with RenewSession
+	SessionUpdated SessionEventType = "updated"
+)
+
+// SessionEvent is a session lifecycle event
+type SessionEvent struct {
+	Type      SessionEventType
+	SessionID string
+	Timestamp time.Time
+}
+
+// SessionEventBus delivers the session lifecycle events of a session manager to the
+// components depending on them, see DefaultSessionManager.SetEventBus
+type SessionEventBus interface {
+	// Publish delivers the event to the subscribers
+	Publish(event SessionEvent)
+	// Subscribe registers a subscriber and returns a function unregistering it
+	Subscribe(fn func(event SessionEvent)) (unsubscribe func())
+}
+
+// sessionSubscriber is a subscriber registered on a DefaultSessionEventBus
+type sessionSubscriber struct {
+	id int
+	fn func(event SessionEvent)
+}
+
+// DefaultSessionEventBus is a SessionEventBus calling the subscribers synchronously, in
+// subscription order, from the goroutine publishing the event. Subscribers must return
+// quickly and may hand the events over to their own goroutine.
+type DefaultSessionEventBus struct {
+	subscribers []sessionSubscriber
+	nextID      int
+	mu          *sync.RWMutex
+}
+
+// NewSessionEventBus creates a new session event bus without subscribers
+func NewSessionEventBus() *DefaultSessionEventBus {
+	return &DefaultSessionEventBus{
+		mu: &sync.RWMutex{},
+	}
+}
+
+// Publish calls the subscribers with the event
+func (b *DefaultSessionEventBus) Publish(event SessionEvent) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, subscriber := range subscribers {
+		subscriber.fn(event)
+	}
+}
+
+// Subscribe registers a subscriber and returns a function unregistering it
+func (b *DefaultSessionEventBus) Subscribe(fn func(event SessionEvent)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	// Copy on write, so Publish can iterate without holding the lock
+	b.subscribers = append(b.subscribers[:len(b.subscribers):len(b.subscribers)], sessionSubscriber{id: id, fn: fn})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subscribers := make([]sessionSubscriber, 0, len(b.subscribers))
+		for _, subscriber := range b.subscribers {
+			if subscriber.id != id {
+				subscribers = append(subscribers, subscriber)
+			}
+		}
+		b.subscribers = subscribers
+	}
+}
+
+// SetEventBus sets the bus on which the manager publishes the creation, expiry (removal by
+// the cleanup), deletion and renewal of its sessions, nil to publish nothing. Events are
+// published after the manager lock is released, so subscribers may call the manager.
+func (s *DefaultSessionManager) SetEventBus(bus SessionEventBus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eventBus = bus
+}
+
+// publish publishes a session event on the event bus, if any
+func (s *DefaultSessionManager) publish(eventType SessionEventType, ids ...string) {
+	s.mu.RLock()
+	bus := s.eventBus
+	s.mu.RUnlock()
+	if bus == nil {
+		return
+	}
+	now := time.Now()
+	for _, id := range ids {
+		bus.Publish(SessionEvent{Type: eventType, SessionID: id, Timestamp: now})
+	}
+}
@@ -0,0 +1,47 @@
+package libserver
+
+import (
+	"errors"
+	"net"
+	"net/http"
+)
+
+// clientIPSessionKey is the session key holding the IP address the session was first used from
+const clientIPSessionKey = "libserver.clientIP"
+
+// errSessionIP is returned when the client IP cannot be recorded in a new session
+var errSessionIP = errors.New("cannot record the client IP in the session")
+
+// SetInvalidateSessionOnIPChange makes the server bind sessions to the IP address of the
+// client, taken from r.RemoteAddr, which mitigates session hijacking. When a request presents
+// a session first used from another address, the session is deleted and the handler gets a
+// new empty session. Sessions whose IP address cannot be recorded are treated the same way,
+// and the request fails with 500 Internal Server Error if it cannot be recorded in the new
+// session. Behind a reverse proxy, RemoteAddr must be rewritten to the client IP.
+func (s *WebServer) SetInvalidateSessionOnIPChange(enabled bool) {
+	s.ipBoundSessions = enabled
+}
+
+// remoteIP returns the IP address of the client, without the port
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkSessionIP records the client IP in the session on first use and returns false if the
+// session was used from another IP address, or if the IP address could not be recorded, when
+// the option is enabled
+func (s *WebServer) checkSessionIP(session Session, r *http.Request) bool {
+	if !s.ipBoundSessions {
+		return true
+	}
+	ip := remoteIP(r)
+	stored, _ := session.Get(clientIPSessionKey).(string)
+	if stored == "" {
+		return session.Set(clientIPSessionKey, ip) == nil
+	}
+	return stored == ip
+}
@@ -1,8 +1,19 @@
 package libserver
 
+import "context"
+
 type SessionManager interface {
 	CreateSession() Session
 	GetSession(id string) Session
 	DeleteSession(id string)
 	HasSession(id string) bool
+	RenewSession(id string) error
+}
+
+// Warmer is implemented by session managers backed by a persistent store that can pre-load
+// the non-expired sessions in memory, so the first request of each session does not incur
+// a store round trip. WebServer.Start calls Warmup before serving and fails if it returns
+// an error.
+type Warmer interface {
+	Warmup(ctx context.Context) error
 }
@@ -0,0 +1,42 @@
+package libserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// SessionMiddleware provides libserver's session management without a WebServer, e.g. with
+// chi, gin, echo or http.DefaultServeMux: the session is read from the cookieName cookie, or
+// created with its cookie when missing or expired, then refreshed and injected into the
+// request context, where GetSession retrieves it. Secure cookies are set when withHTTPS is true.
+func SessionMiddleware(manager SessionManager, cookieName string, withHTTPS bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var session Session
+			if cookie, err := r.Cookie(cookieName); err == nil {
+				if existing := manager.GetSession(cookie.Value); existing != nil {
+					if existing.IsExpired() {
+						manager.DeleteSession(cookie.Value)
+					} else {
+						session = existing
+					}
+				}
+			}
+			if session == nil {
+				session = manager.CreateSession()
+				http.SetCookie(w, &http.Cookie{
+					Name:     cookieName,
+					Value:    session.Id(),
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   withHTTPS,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			session.Update()
+
+			ctx := context.WithValue(r.Context(), sessionContextKey{}, session)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package libserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultSessionRenewalThreshold is the fraction of the session TTL left under which the
+// session cookie is renewed
+const DefaultSessionRenewalThreshold = 0.1
+
+// SetSessionRenewalThreshold sets the fraction of its TTL a session must have left for its
+// cookie to be renewed, DefaultSessionRenewalThreshold (within 10% of the TTL) by default.
+// The renewed cookie is re-issued with the session ID and the same options as when the
+// session was created, so it stays a browser-session cookie, and the session timer is reset. Renewal applies to sessions exposing LastAccessedAt and
+// ExpirationDuration, such as DefaultSession, and never to sessions with AbsoluteTTL.
+// A fraction of 0 or less disables renewal.
+func (s *WebServer) SetSessionRenewalThreshold(fraction float64) {
+	s.renewalThreshold = fraction
+}
+
+// renewSessionCookie renews the cookie of a session close to expiring
+func (s *WebServer) renewSessionCookie(w http.ResponseWriter, cookie *http.Cookie, session Session) {
+	if s.renewalThreshold <= 0 {
+		return
+	}
+	timed, ok := session.(interface {
+		LastAccessedAt() time.Time
+		ExpirationDuration() time.Duration
+	})
+	if !ok {
+		return
+	}
+	if strategy, ok := session.(interface{ TTLStrategy() TTLStrategy }); ok && strategy.TTLStrategy() == AbsoluteTTL {
+		return
+	}
+	ttl := timed.ExpirationDuration()
+	remaining := time.Until(timed.LastAccessedAt().Add(ttl))
+	if remaining > time.Duration(float64(ttl)*s.renewalThreshold) {
+		return
+	}
+
+	session.Update()
+	setSessionCookie(w, cookie)
+}
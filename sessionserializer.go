@@ -0,0 +1,74 @@
+package libserver
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// SessionSerializer converts session data to and from bytes for persistent session stores
+type SessionSerializer interface {
+	Marshal(data map[string]any) ([]byte, error)
+	Unmarshal(b []byte) (map[string]any, error)
+}
+
+// JSONSessionSerializer serializes session data as JSON.
+// Values are decoded as generic JSON types (float64, string, map[string]any, ...).
+type JSONSessionSerializer struct{}
+
+// Marshal encodes the session data as JSON
+func (JSONSessionSerializer) Marshal(data map[string]any) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// Unmarshal decodes JSON session data
+func (JSONSessionSerializer) Unmarshal(b []byte) (map[string]any, error) {
+	data := make(map[string]any)
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GobSessionSerializer serializes session data with encoding/gob, preserving Go types
+// such as time.Time and custom structs. Custom types stored in sessions must be
+// registered with gob.Register.
+type GobSessionSerializer struct{}
+
+// Marshal encodes the session data with gob
+func (GobSessionSerializer) Marshal(data map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob session data
+func (GobSessionSerializer) Unmarshal(b []byte) (map[string]any, error) {
+	data := make(map[string]any)
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// MessagePackSessionSerializer serializes session data as MessagePack, a compact
+// binary format that round-trips time.Time values
+type MessagePackSessionSerializer struct{}
+
+// Marshal encodes the session data as MessagePack
+func (MessagePackSessionSerializer) Marshal(data map[string]any) ([]byte, error) {
+	return msgpack.Marshal(data)
+}
+
+// Unmarshal decodes MessagePack session data
+func (MessagePackSessionSerializer) Unmarshal(b []byte) (map[string]any, error) {
+	data := make(map[string]any)
+	if err := msgpack.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
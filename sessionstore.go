@@ -0,0 +1,32 @@
+package libserver
+
+// SessionStore persists sessions, e.g. in Redis or in files, for custom session managers.
+// SaveDiff writes only the given keys of the session, e.g. with HSET for the set keys and
+// HDEL for the deleted ones (the keys for which Has returns false), instead of serializing
+// the whole session.
+type SessionStore interface {
+	Save(session Session) error
+	SaveDiff(session Session, changedKeys []string) error
+}
+
+// SaveSession persists the session in the store: sessions tracking their changes, such as
+// DefaultSession, are saved with SaveDiff and only when they changed, then marked saved.
+// Other sessions are saved entirely with Save.
+func SaveSession(store SessionStore, session Session) error {
+	tracked, ok := session.(interface {
+		ChangedKeys() []string
+		MarkSaved()
+	})
+	if !ok {
+		return store.Save(session)
+	}
+	changed := tracked.ChangedKeys()
+	if len(changed) == 0 {
+		return nil
+	}
+	if err := store.SaveDiff(session, changed); err != nil {
+		return err
+	}
+	tracked.MarkSaved()
+	return nil
+}
@@ -0,0 +1,34 @@
+package libserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SetSessionTokenHeader enables session identification by a token in the given request
+// header (e.g. X-Session-Token), for clients that cannot use cookies. The token may be
+// prefixed with "Bearer ". The session cookie is checked first. When the request carries
+// no cookie at all, the session token is also written to the same response header so the
+// client can send it back.
+func (s *WebServer) SetSessionTokenHeader(header string) {
+	s.tokenHeader = header
+}
+
+// sessionToken returns the session token of the request, if any
+func (s *WebServer) sessionToken(r *http.Request) string {
+	if s.tokenHeader == "" {
+		return ""
+	}
+	token := strings.TrimSpace(r.Header.Get(s.tokenHeader))
+	if bearer, ok := strings.CutPrefix(token, "Bearer "); ok {
+		token = strings.TrimSpace(bearer)
+	}
+	return token
+}
+
+// writeSessionToken writes the session token header in responses to non-browser clients
+func (s *WebServer) writeSessionToken(w http.ResponseWriter, r *http.Request, session Session) {
+	if s.tokenHeader != "" && r.Header.Get("Cookie") == "" {
+		w.Header().Set(s.tokenHeader, session.Id())
+	}
+}
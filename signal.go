@@ -0,0 +1,36 @@
+package libserver
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ListenAndServeWithSignalHandling starts the server and blocks until one of the signals
+// (SIGINT and SIGTERM if none are given) is received, then stops it gracefully with Stop.
+// It returns nil on a clean shutdown, and the error of Start or Stop otherwise.
+func (s *WebServer) ListenAndServeWithSignalHandling(signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), signals...)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Start()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		// Restore the default behavior, so a second signal kills the process
+		stop()
+		if err := s.Stop(); err != nil {
+			return err
+		}
+		return <-errCh
+	}
+}
@@ -0,0 +1,135 @@
+package libserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// serverStats holds the counters updated by the handler wrapper
+type serverStats struct {
+	startedAt     atomic.Int64
+	requestsTotal atomic.Int64
+	errorsTotal   atomic.Int64
+	inFlight      atomic.Int64
+	routes        sync.Map
+}
+
+// routeCounters holds the counters of a route
+type routeCounters struct {
+	requests      atomic.Int64
+	bytesReceived atomic.Int64
+	bytesSent     atomic.Int64
+	errors        atomic.Int64
+	latency       atomic.Int64
+}
+
+// RouteStats is a snapshot of the metrics of a route
+type RouteStats struct {
+	RequestCount  int64   `json:"request_count"`
+	BytesReceived int64   `json:"bytes_received"`
+	BytesSent     int64   `json:"bytes_sent"`
+	ErrorCount    int64   `json:"error_count"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+}
+
+// snapshot returns the current metrics of the route
+func (c *routeCounters) snapshot() RouteStats {
+	stats := RouteStats{
+		RequestCount:  c.requests.Load(),
+		BytesReceived: c.bytesReceived.Load(),
+		BytesSent:     c.bytesSent.Load(),
+		ErrorCount:    c.errors.Load(),
+	}
+	if stats.RequestCount > 0 {
+		stats.AvgLatencyMs = float64(c.latency.Load()) / float64(stats.RequestCount) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+// recordRoute updates the counters of the route of a served request
+func (s *serverStats) recordRoute(r *http.Request, rw *responseWriter, elapsed time.Duration, failed bool) {
+	value, ok := s.routes.Load(r.Pattern)
+	if !ok {
+		value, _ = s.routes.LoadOrStore(r.Pattern, &routeCounters{})
+	}
+	counters := value.(*routeCounters)
+	counters.requests.Add(1)
+	if r.ContentLength > 0 {
+		counters.bytesReceived.Add(r.ContentLength)
+	}
+	counters.bytesSent.Add(rw.bytesWritten)
+	if failed {
+		counters.errors.Add(1)
+	}
+	counters.latency.Add(int64(elapsed))
+}
+
+// ServerStats is a snapshot of the server health metrics
+type ServerStats struct {
+	UptimeSeconds  float64 `json:"uptime_seconds"`
+	RequestsTotal  int64   `json:"requests_total"`
+	ErrorsTotal    int64   `json:"errors_total"`
+	SessionsActive int     `json:"sessions_active"`
+	MemoryBytes    uint64  `json:"memory_bytes"`
+}
+
+// Stats returns a snapshot of the server health metrics. Errors are responses with a 4xx
+// or 5xx status code and requests whose handler panicked; active sessions are only reported by session managers exposing a
+// SessionCount method, such as DefaultSessionManager.
+func (s *WebServer) Stats() ServerStats {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	stats := ServerStats{
+		RequestsTotal: s.stats.requestsTotal.Load(),
+		ErrorsTotal:   s.stats.errorsTotal.Load(),
+		MemoryBytes:   memStats.Alloc,
+	}
+	if startedAt := s.stats.startedAt.Load(); startedAt != 0 {
+		stats.UptimeSeconds = time.Since(time.Unix(0, startedAt)).Seconds()
+	}
+	if counter, ok := s.sessionManager.(interface{ SessionCount() int }); ok {
+		stats.SessionsActive = counter.SessionCount()
+	}
+	return stats
+}
+
+// RouteStats returns a snapshot of the metrics of every route, keyed by the pattern the
+// route was registered with. Bytes received are taken from the request Content-Length, so
+// chunked request bodies are not counted. Requests of a custom router set with WithRouter
+// are reported under the pattern it sets on http.Request.Pattern, if any.
+func (s *WebServer) RouteStats() map[string]RouteStats {
+	stats := make(map[string]RouteStats)
+	s.stats.routes.Range(func(key, value any) bool {
+		stats[key.(string)] = value.(*routeCounters).snapshot()
+		return true
+	})
+	return stats
+}
+
+// GetRouteStats returns the metrics of the route registered with pattern, or false if no
+// route is registered with it. Unlike RouteStats, it does not copy the metrics of every
+// route, so it is cheap to call on every metrics scrape.
+func (s *WebServer) GetRouteStats(pattern string) (RouteStats, bool) {
+	if value, ok := s.stats.routes.Load(pattern); ok {
+		return value.(*routeCounters).snapshot(), true
+	}
+	return RouteStats{}, slices.Contains(s.routes, pattern)
+}
+
+// EnableStats serves the server health metrics as JSON at the given path.
+// Requests to the stats endpoint do not create sessions and are not counted.
+// It returns ErrCustomRouter if the server uses a custom router, see WithRouter, and the
+// errors of Register if the path is invalid or conflicts with a route.
+func (s *WebServer) EnableStats(path string) error {
+	return s.registerEndpoint(s.mux, path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(s.Stats())
+	}))
+}
@@ -0,0 +1,104 @@
+package libserver
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// TemplateRenderer renders HTML templates loaded from a directory, caching the parsed templates
+type TemplateRenderer struct {
+	dir    string
+	cache  map[string]*template.Template
+	reload atomic.Bool
+	mu     *sync.RWMutex
+}
+
+// NewTemplateRenderer creates a renderer loading templates from the given directory
+func NewTemplateRenderer(dir string) *TemplateRenderer {
+	return &TemplateRenderer{
+		dir:   dir,
+		cache: make(map[string]*template.Template),
+		mu:    &sync.RWMutex{},
+	}
+}
+
+// SetTemplateReloadMode enables or disables reloading templates from disk on every Render call.
+// Reload mode is meant for development and can be switched at runtime.
+func (t *TemplateRenderer) SetTemplateReloadMode(enabled bool) {
+	t.reload.Store(enabled)
+	if !enabled {
+		// Templates cached before reload mode was enabled may be stale
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.cache = make(map[string]*template.Template)
+	}
+}
+
+// IsTemplateReloadMode returns true if templates are reloaded on every Render call
+func (t *TemplateRenderer) IsTemplateReloadMode() bool {
+	return t.reload.Load()
+}
+
+// Render executes the named template file with the given data and writes it as HTML. The
+// status code is left to the caller, which may write it before, 200 OK being sent otherwise.
+func (t *TemplateRenderer) Render(w http.ResponseWriter, name string, data any) error {
+	return t.renderStatus(w, 0, name, data)
+}
+
+// renderStatus executes the named template file and writes it as HTML with the status code,
+// or without writing the status code when it is 0
+func (t *TemplateRenderer) renderStatus(w http.ResponseWriter, statusCode int, name string, data any) error {
+	tmpl, err := t.lookup(name)
+	if err != nil {
+		return err
+	}
+	return writeTemplate(w, statusCode, tmpl, data)
+}
+
+// writeTemplate executes the template and writes it as HTML with the status code, or without
+// writing the status code when it is 0
+func writeTemplate(w http.ResponseWriter, statusCode int, tmpl *template.Template, data any) error {
+	// Execute into a buffer so that a failing template does not send a partial response
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if statusCode != 0 {
+		w.WriteHeader(statusCode)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// lookup returns the named template from the cache, parsing it if needed
+func (t *TemplateRenderer) lookup(name string) (*template.Template, error) {
+	if t.reload.Load() {
+		return t.parse(name)
+	}
+
+	t.mu.RLock()
+	tmpl, ok := t.cache[name]
+	t.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := t.parse(name)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cache[name] = tmpl
+	return tmpl, nil
+}
+
+// parse parses the named template file from disk
+func (t *TemplateRenderer) parse(name string) (*template.Template, error) {
+	return template.ParseFiles(filepath.Join(t.dir, name))
+}
@@ -0,0 +1,71 @@
+package libserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// tenantSessionKey is the session key holding the tenant the session belongs to
+const tenantSessionKey = "libserver.tenant"
+
+// SetTenantResolver sets a function extracting the tenant identifier from a request
+// (from the subdomain, a header or a path segment). Sessions are then isolated per
+// tenant: the session cookie name is suffixed with the tenant identifier, session IDs
+// are namespaced as tenantID:sessionID when the session manager supports
+// CreateSessionWithID, and a session is never used for a request of another tenant.
+func (s *WebServer) SetTenantResolver(fn func(r *http.Request) string) {
+	s.tenantResolver = fn
+}
+
+// resolveTenant returns the tenant of the request, or an empty string without resolver
+func (s *WebServer) resolveTenant(r *http.Request) string {
+	if s.tenantResolver == nil {
+		return ""
+	}
+	return s.tenantResolver(r)
+}
+
+// tenantCookieName returns the session cookie name scoped to the tenant
+func (s *WebServer) tenantCookieName(tenant string) string {
+	if tenant == "" {
+		return s.GetSessionCookieName()
+	}
+	// Keep only the characters valid in a cookie name
+	sanitized := strings.Map(func(r rune) rune {
+		if r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.", r)) {
+			return r
+		}
+		return '_'
+	}, tenant)
+	return s.GetSessionCookieName() + "_" + sanitized
+}
+
+// createTenantSession creates a session for the tenant, namespacing its ID when possible.
+// The session is deleted if it cannot be bound to the tenant.
+func (s *WebServer) createTenantSession(manager SessionManager, tenant string) (Session, error) {
+	if tenant == "" {
+		return manager.CreateSession(), nil
+	}
+	var session Session
+	if creator, ok := manager.(interface{ CreateSessionWithID(id string) Session }); ok {
+		session = creator.CreateSessionWithID(tenant + ":" + uuid.New().String())
+	} else {
+		session = manager.CreateSession()
+	}
+	if err := session.Set(tenantSessionKey, tenant); err != nil {
+		manager.DeleteSession(session.Id())
+		return nil, err
+	}
+	return session, nil
+}
+
+// belongsToTenant returns true if the session may be used for the tenant
+func belongsToTenant(session Session, tenant string) bool {
+	if tenant == "" {
+		return true
+	}
+	owner, _ := session.Get(tenantSessionKey).(string)
+	return owner == tenant
+}
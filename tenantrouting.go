@@ -0,0 +1,56 @@
+package libserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TenantIDKey is the context key for accessing the tenant identified by TenantRoutingMiddleware
+const TenantIDKey ContextKey = "tenantID"
+
+// ErrUnknownTenant is returned by a TenantRegistry for tenants it does not know
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantRegistry resolves the handler serving the requests of a tenant
+type TenantRegistry interface {
+	// Resolve returns the handler of the tenant, or ErrUnknownTenant if the tenant does not exist
+	Resolve(id string) (http.Handler, error)
+}
+
+// TenantRoutingMiddleware delegates every request to the handler of the tenant identified by
+// the tenantHeaderName header, in place of the wrapped handler. Requests without the header
+// get a 400, requests of unknown tenants a 404 and registry failures a 500. The tenant ID is
+// injected into the request context.
+func TenantRoutingMiddleware(tenantHeaderName string, registry TenantRegistry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := strings.TrimSpace(r.Header.Get(tenantHeaderName))
+			if tenant == "" {
+				http.Error(w, "missing "+tenantHeaderName+" header", http.StatusBadRequest)
+				return
+			}
+			handler, err := registry.Resolve(tenant)
+			if errors.Is(err, ErrUnknownTenant) || err == nil && handler == nil {
+				http.Error(w, "unknown tenant", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), TenantIDKey, tenant)
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetTenantIDFromContext retrieves the tenant identified by TenantRoutingMiddleware
+func GetTenantIDFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(TenantIDKey).(string); ok {
+		return tenant
+	}
+	return ""
+}
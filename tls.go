@@ -0,0 +1,41 @@
+package libserver
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// loadCertificate loads the certificate and key files and makes them the served certificate
+func (s *WebServer) loadCertificate() error {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+	s.certificate.Store(&cert)
+	return nil
+}
+
+// getCertificate returns the current certificate, it is used as tls.Config.GetCertificate
+func (s *WebServer) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return s.certificate.Load(), nil
+}
+
+// configureTLS makes the server pick up the current certificate on every new connection,
+// unless certificates were configured explicitly in its TLS config
+func (s *WebServer) configureTLS(server *http.Server) {
+	if server.TLSConfig == nil {
+		server.TLSConfig = &tls.Config{}
+	}
+	if server.TLSConfig.GetCertificate == nil && len(server.TLSConfig.Certificates) == 0 {
+		server.TLSConfig.GetCertificate = s.getCertificate
+	}
+	configureFingerprinting(server)
+}
+
+// ReloadTLS reloads the certificate and key files given to EnableHTTPS. New connections
+// use the reloaded certificate immediately, while established connections keep the
+// certificate they were negotiated with until they close. On error, the current
+// certificate is kept.
+func (s *WebServer) ReloadTLS() error {
+	return s.loadCertificate()
+}
@@ -0,0 +1,84 @@
+package libserver
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ForceTraceHeader is the request header forcing the trace of a request when set to "true",
+// for the requests accepted by the allowForce function of TraceRequestMiddleware
+const ForceTraceHeader = "X-Force-Trace"
+
+// traceWindowMin is the minimum duration of execution kept by the flight recorder
+const traceWindowMin = 10 * time.Second
+
+// TraceRequestMiddleware writes a runtime/trace execution trace for every request slower than
+// minDuration, or sent with the X-Force-Trace: true header and accepted by allowForce, to a
+// file named {timestamp}-{requestID}.trace in outputDir, and logs its path. Each trace is a
+// snapshot of several seconds of execution written to disk, so the header must never be
+// honored on untrusted traffic: allowForce should only accept developers or admin addresses,
+// and a nil allowForce ignores the header. The request ID is taken from
+// the X-Request-ID header when present. Tracing relies on the runtime flight recorder, which
+// keeps the recent execution in memory so traces include the whole request; since only one
+// flight recorder may run per process, the middleware is a no-op if it cannot start one.
+func TraceRequestMiddleware(minDuration time.Duration, outputDir string, allowForce func(r *http.Request) bool) func(http.Handler) http.Handler {
+	recorder := trace.NewFlightRecorder(trace.FlightRecorderConfig{MinAge: max(2*minDuration, traceWindowMin)})
+	if err := recorder.Start(); err != nil {
+		slog.Warn("libserver: request tracing disabled", "error", err)
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	// Only one snapshot of the flight recorder may be written at a time
+	var writeMu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			elapsed := time.Since(start)
+			if elapsed < minDuration && !forcesTrace(r, allowForce) {
+				return
+			}
+
+			requestID := r.Header.Get("X-Request-ID")
+			if requestID == "" {
+				requestID, _ = randomToken()
+			}
+			requestID = strings.Map(func(c rune) rune {
+				if c == '/' || c == '\\' || c == os.PathSeparator {
+					return '_'
+				}
+				return c
+			}, requestID)
+			path := filepath.Join(outputDir, fmt.Sprintf("%s-%s.trace", start.UTC().Format("20060102T150405.000000"), requestID))
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			file, err := os.Create(path)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "libserver: cannot write trace", "method", r.Method, "path", r.URL.Path, "error", err)
+				return
+			}
+			defer file.Close()
+			if _, err := recorder.WriteTo(file); err != nil {
+				slog.ErrorContext(r.Context(), "libserver: cannot write trace", "method", r.Method, "path", r.URL.Path, "error", err)
+				return
+			}
+			slog.InfoContext(r.Context(), "libserver: trace written",
+				"method", r.Method, "path", r.URL.Path, "duration", elapsed, "file", path)
+		})
+	}
+}
+
+// forcesTrace returns true if the request asks for its trace and is allowed to
+func forcesTrace(r *http.Request, allowForce func(r *http.Request) bool) bool {
+	return allowForce != nil && r.Header.Get(ForceTraceHeader) == "true" && allowForce(r)
+}
@@ -0,0 +1,138 @@
+package libserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// routeFinder is implemented by routers able to report the pattern matching a request,
+// such as http.ServeMux
+type routeFinder interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
+}
+
+// withPath returns a shallow copy of the request for another path
+func withPath(r *http.Request, path string) *http.Request {
+	clone := new(http.Request)
+	*clone = *r
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+	clone.URL = &u
+	return clone
+}
+
+// patternPath returns the path of a ServeMux pattern, without its method and host
+func patternPath(pattern string) string {
+	if _, rest, found := strings.Cut(pattern, " "); found {
+		pattern = strings.TrimSpace(rest)
+	}
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return pattern
+}
+
+// canonicalPath returns the path with or without its trailing slash under which the route
+// of the request is registered, or an empty string if the path is already canonical
+func canonicalPath(finder routeFinder, r *http.Request) string {
+	path := r.URL.Path
+	if path == "" || path == "/" {
+		return ""
+	}
+	_, pattern := finder.Handler(r)
+
+	if strings.HasSuffix(path, "/") {
+		// The route is only registered without the trailing slash
+		if pattern != "" {
+			return ""
+		}
+		trimmed := strings.TrimRight(path, "/")
+		if trimmed == "" {
+			return ""
+		}
+		if _, pattern := finder.Handler(withPath(r, trimmed)); pattern != "" {
+			return trimmed
+		}
+		return ""
+	}
+
+	// The ServeMux redirects /tree to /tree/ when only /tree/ is registered: the matched
+	// pattern then ends with a slash and has exactly one more segment than the path
+	patPath := patternPath(pattern)
+	if strings.HasSuffix(patPath, "/") && strings.Count(patPath, "/") == strings.Count(path, "/")+1 {
+		return path + "/"
+	}
+	return ""
+}
+
+// TrailingSlashMiddleware makes the routes of router reachable with or without a trailing
+// slash. router must report the pattern matching a request, like http.ServeMux, and is
+// usually the handler the middleware wraps; the middleware must run before routing, since
+// requests with the wrong form of the path do not reach the route handlers. It follows the
+// Go 1.22 ServeMux rules: when a path with a trailing slash has no route but the path without
+// it has one, or the reverse, the request is either redirected to the registered form with
+// redirectCode (308 Permanent Redirect if 0) when redirect is true, or rewritten to it and
+// served directly. It panics if router cannot report the pattern of a request. On a
+// WebServer, use SetTrailingSlashHandling instead.
+func TrailingSlashMiddleware(router http.Handler, redirect bool, redirectCode int) func(http.Handler) http.Handler {
+	finder, ok := router.(routeFinder)
+	if !ok {
+		panic(fmt.Sprintf("libserver: TrailingSlashMiddleware: router %T does not report the pattern of a request", router))
+	}
+	if redirectCode == 0 {
+		redirectCode = http.StatusPermanentRedirect
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			canonical := canonicalPath(finder, r)
+			if canonical == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if redirect {
+				target := canonical
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, redirectCode)
+				return
+			}
+			next.ServeHTTP(w, withPath(r, canonical))
+		})
+	}
+}
+
+// ErrNoRouteFinder is returned by SetTrailingSlashHandling when the custom router of the
+// server cannot report the pattern matching a request
+var ErrNoRouteFinder = errors.New("router does not report the pattern of a request, as http.ServeMux does")
+
+// trailingSlashConfig is the configuration of SetTrailingSlashHandling
+type trailingSlashConfig struct {
+	redirect     bool
+	redirectCode int
+}
+
+// SetTrailingSlashHandling makes the routes of the server and of its listeners reachable
+// with or without a trailing slash, see TrailingSlashMiddleware. The paths are fixed before
+// routing, so it applies from Start. It returns ErrNoRouteFinder if the router given to
+// WithRouter cannot report the pattern matching a request.
+func (s *WebServer) SetTrailingSlashHandling(redirect bool, redirectCode int) error {
+	if s.router != nil {
+		if _, ok := s.router.(routeFinder); !ok {
+			return ErrNoRouteFinder
+		}
+	}
+	s.trailingSlash = &trailingSlashConfig{redirect: redirect, redirectCode: redirectCode}
+	return nil
+}
+
+// withTrailingSlash wraps the handler of a server routing with router when enabled
+func (s *WebServer) withTrailingSlash(router, handler http.Handler) http.Handler {
+	if s.trailingSlash == nil {
+		return handler
+	}
+	return TrailingSlashMiddleware(router, s.trailingSlash.redirect, s.trailingSlash.redirectCode)(handler)
+}
@@ -0,0 +1,36 @@
+package libserver
+
+// TypedServerData is a strongly-typed slot of a ServerData, e.g.
+// DBPool := NewTypedServerData[*sql.DB](data, "db")
+type TypedServerData[T any] struct {
+	data *ServerData
+	key  string
+}
+
+// NewTypedServerData creates a typed slot stored under key in data
+func NewTypedServerData[T any](data *ServerData, key string) *TypedServerData[T] {
+	return &TypedServerData[T]{data: data, key: key}
+}
+
+// Get returns the value of the slot, or the zero value of T if it is unset or holds
+// a value of another type
+func (t *TypedServerData[T]) Get() T {
+	value, _ := t.data.Get(t.key).(T)
+	return value
+}
+
+// Set stores the value of the slot
+func (t *TypedServerData[T]) Set(v T) {
+	t.data.Set(t.key, v)
+}
+
+// Has checks if the slot holds a value of type T
+func (t *TypedServerData[T]) Has() bool {
+	_, ok := t.data.Get(t.key).(T)
+	return ok
+}
+
+// Delete removes the value of the slot
+func (t *TypedServerData[T]) Delete() {
+	t.data.Delete(t.key)
+}
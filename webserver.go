@@ -2,8 +2,16 @@ package libserver
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ContextKey is the type used for context keys to avoid collisions
@@ -14,50 +22,281 @@ const (
 	ServerDataKey ContextKey = "serverData"
 )
 
+// sessionContextKey is the context key of the session, unexported so that no other package
+// can collide with it
+type sessionContextKey struct{}
+
+// ErrServerStarted is returned when configuring a setting that cannot change once the server has started
+var ErrServerStarted = errors.New("server already started")
+
+// DefaultDrainTimeout is the default time Stop waits for in-flight requests and background tasks
+const DefaultDrainTimeout = 30 * time.Second
+
+// DefaultReadHeaderTimeout is the default time allowed to read the request headers. It is
+// the recommended value for internet-facing servers: long enough for slow clients, short
+// enough to close the connections of Slowloris attacks sending their headers slowly.
+const DefaultReadHeaderTimeout = 5 * time.Second
+
 // WebServer is the main HTTP server with integrated session management
 type WebServer struct {
-	applicationName string
-	address         string
-	port            int
-	server          *http.Server
-	mux             *http.ServeMux
-	data            *ServerData
-	certFile        string
-	keyFile         string
-	withHttps       bool
-	sessionManager  SessionManager
+	applicationName  string
+	cookieName       string
+	address          string
+	port             int
+	server           *http.Server
+	mux              *http.ServeMux
+	data             *ServerData
+	certFile         string
+	keyFile          string
+	withHttps        bool
+	sessionManager   SessionManager
+	listeners        []*http.Server
+	tasks            []backgroundTask
+	cancelTasks      context.CancelFunc
+	tasksWg          sync.WaitGroup
+	drainTimeout     time.Duration
+	bufferBody       bool
+	auditLogger      func(event SessionAuditEvent)
+	auditAccess      bool
+	protocolHandlers map[string]func(conn net.Conn)
+	listener         net.Listener
+	started          bool
+	stats            serverStats
+	tenantResolver   func(r *http.Request) string
+	tokenHeader      string
+	certificate      atomic.Pointer[tls.Certificate]
+	router           http.Handler
+	middleware       []func(http.Handler) http.Handler
+	middlewareLabels []string
+	routes           []string
+	debug            bool
+	corsConfig       *CORSConfig
+	corsHeaders      bool
+	h2c              bool
+	sameSite         http.SameSite
+	requestHooks     []func(r *http.Request)
+	responseHooks    []func(r *http.Request, statusCode int, bytesWritten int64)
+	ipBoundSessions  bool
+	flushEvery       int
+	keepAliveOff     bool
+	idleTimeout      time.Duration
+	renewalThreshold float64
+	apmHook          APMHook
+	contentType      string
+	optionsRoutes    map[string]*optionsRoute
+	templates        *TemplateRenderer
+	semaphore        chan struct{}
+	queueTimeout     time.Duration
+	healthChecks     map[string]*healthEndpoint
+	groupCount       int
+	serverHeader     *string
+	idValidator      func(id string) bool
+	slogger          *slog.Logger
+	trailingSlash    *trailingSlashConfig
+	chainVersion     atomic.Uint64
+	configMu         *sync.RWMutex
+	mu               *sync.Mutex
+}
+
+// WebServerOption configures a WebServer at construction
+type WebServerOption func(*WebServer)
+
+// WithRouter replaces the internal http.ServeMux with the given router (e.g. a third-party
+// router). Routes must then be registered on the router: AddHandlerFunc and AddHandler
+// panic. Session and server data injection still wraps every request of the router.
+func WithRouter(router http.Handler) WebServerOption {
+	return func(s *WebServer) {
+		s.router = router
+	}
+}
+
+// SessionIDValidator makes the server check the format of the session IDs presented by
+// clients, e.g. a prefix identifying the originating datacenter, before looking them up:
+// requests whose session ID fails validation get a new session, as if they had none.
+func SessionIDValidator(validate func(id string) bool) WebServerOption {
+	return func(s *WebServer) {
+		s.idValidator = validate
+	}
 }
 
 // NewWebServer creates a new WebServer instance
-func NewWebServer(name, address string, port int) *WebServer {
-	return &WebServer{
-		address:         address,
-		port:            port,
-		server:          &http.Server{Addr: fmt.Sprintf("%s:%d", address, port)},
-		mux:             http.NewServeMux(),
-		data:            NewServerData(),
-		withHttps:       false,
-		applicationName: name,
+func NewWebServer(name, address string, port int, options ...WebServerOption) *WebServer {
+	s := &WebServer{
+		address:          address,
+		port:             port,
+		server:           &http.Server{Addr: fmt.Sprintf("%s:%d", address, port), ReadHeaderTimeout: DefaultReadHeaderTimeout},
+		mux:              http.NewServeMux(),
+		data:             NewServerData(),
+		withHttps:        false,
+		applicationName:  name,
+		drainTimeout:     DefaultDrainTimeout,
+		renewalThreshold: DefaultSessionRenewalThreshold,
+		queueTimeout:     DefaultConcurrencyQueueTimeout,
+		configMu:         &sync.RWMutex{},
+		mu:               &sync.Mutex{},
+	}
+	for _, option := range options {
+		option(s)
 	}
+	return s
 }
 
-// Start starts the web server
+// Start starts the web server and blocks until it stops. It returns nil when the
+// server is shut down with Stop or Drain, and the error otherwise. The addresses of the
+// server and of its listeners are all bound before any is served, so Start returns at
+// once if one of them is unavailable.
 func (s *WebServer) Start() error {
+	if err := s.validateCookieConfig(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+	s.stats.startedAt.Store(time.Now().UnixNano())
+
 	// Set default session manager if none is provided
 	if s.sessionManager == nil {
 		s.sessionManager = NewDefaultSessionManager()
 	}
 	s.data.SetSessionManager(s.sessionManager)
 
-	// Set the handler
-	s.server.Handler = s.mux
+	// Pre-load the sessions of managers backed by a persistent store
+	if warmer, ok := s.sessionManager.(Warmer); ok {
+		if err := warmer.Warmup(context.Background()); err != nil {
+			return fmt.Errorf("session warm-up failed: %w", err)
+		}
+	}
+
+	// Set the handler, a custom router is wrapped as a whole with the session injection
+	s.server.Handler = s.withTrailingSlash(s.mux, s.mux)
+	if s.router != nil {
+		s.server.Handler = s.withTrailingSlash(s.router, s.wrapHandler(s.router.ServeHTTP))
+	}
+	s.server.Handler = s.withH2C(s.withServerHeader(s.server.Handler))
+	for _, listener := range s.listeners {
+		listener.Handler = s.withH2C(s.withServerHeader(s.withTrailingSlash(listener.Handler, listener.Handler)))
+	}
+
+	// Load the certificate served through tls.Config.GetCertificate, so it can be reloaded
+	if s.withHttps {
+		if err := s.loadCertificate(); err != nil {
+			return err
+		}
+	}
+
+	// Bind every address before serving any, so Start fails at once if one is unavailable
+	listeners, err := s.bindListeners()
+	if err != nil {
+		return err
+	}
+
+	// Start the background tasks
+	s.startBackgroundTasks()
+
+	// Serve the secondary listeners, each in its own goroutine
+	errCh := make(chan error, len(s.listeners))
+	for i, listener := range s.listeners {
+		go func(server *http.Server, ln net.Listener) {
+			errCh <- s.serve(server, ln)
+		}(listener, listeners[i+1])
+	}
+
+	err = s.serve(s.server, listeners[0])
+	if err != nil && err != http.ErrServerClosed {
+		// The primary listener failed, don't leave the secondary ones running
+		s.shutdownListeners(context.Background())
+		return err
+	}
+	// Report the first secondary listener failure, if any
+	for range s.listeners {
+		if listenerErr := <-errCh; listenerErr != nil && listenerErr != http.ErrServerClosed {
+			return listenerErr
+		}
+	}
+	// A clean shutdown through Stop or Drain is not an error
+	return nil
+}
+
+// bindListeners opens the listeners of the primary server and of the secondary ones, in
+// that order, closing the ones already open if an address cannot be bound
+func (s *WebServer) bindListeners() ([]net.Listener, error) {
+	servers := append([]*http.Server{s.server}, s.listeners...)
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, server := range servers {
+		addr := server.Addr
+		if addr == "" {
+			addr = ":http"
+			if s.withHttps {
+				addr = ":https"
+			}
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, open := range listeners {
+				open.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+
+	s.mu.Lock()
+	s.listener = listeners[0]
+	s.mu.Unlock()
+	for _, ln := range listeners {
+		s.logger().Info("libserver: listening", "addr", ln.Addr().String(), "https", s.withHttps)
+	}
+	return listeners, nil
+}
 
-	// if https is enabled, use ListenAndServeTLS
+// serve serves the given server on its bound listener using the HTTPS settings of the web server
+func (s *WebServer) serve(server *http.Server, ln net.Listener) error {
+	// Close the listener if serving fails before the server takes ownership of it
+	defer ln.Close()
 	if s.withHttps {
-		return s.server.ListenAndServeTLS(s.certFile, s.keyFile)
+		s.configureTLS(server)
 	}
-	// else use ListenAndServe
-	return s.server.ListenAndServe()
+	// if protocol handlers are registered, dispatch the TLS connections by ALPN protocol
+	if s.withHttps && len(s.protocolHandlers) > 0 && server == s.server {
+		return s.serveALPN(server, ln)
+	}
+	// if https is enabled, use ServeTLS with the certificate from the TLS config
+	if s.withHttps {
+		return server.ServeTLS(ln, "", "")
+	}
+	// else use Serve
+	return server.Serve(ln)
+}
+
+// BoundPort returns the port the server is actually listening on, which differs from
+// the configured port when it is 0. It returns 0 until the listener is open.
+func (s *WebServer) BoundPort() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return 0
+	}
+	if addr, ok := s.listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// AddListener registers a secondary listener on the given address and port.
+// Handlers added to the returned route group are only served by this listener
+// and share the server's session manager and server data. The listener starts
+// and stops with the web server.
+func (s *WebServer) AddListener(address string, port int) *RouteGroup {
+	mux := http.NewServeMux()
+	listener := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", address, port),
+		Handler:           mux,
+		ReadHeaderTimeout: s.server.ReadHeaderTimeout,
+	}
+	s.applyKeepAlive(listener)
+	s.listeners = append(s.listeners, listener)
+	return newRouteGroup(s, mux)
 }
 
 // EnableHTTPS enables HTTPS with the provided certificate and key files
@@ -67,63 +306,366 @@ func (s *WebServer) EnableHTTPS(certFile, keyFile string) {
 	s.withHttps = true
 }
 
-// Stop gracefully shuts down the web server
+// Stop gracefully shuts down the web server, waiting at most the drain timeout
+// for in-flight requests before closing the remaining connections
 func (s *WebServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.drainTimeout)
+	defer cancel()
+	err := s.Drain(ctx)
+	if err != nil {
+		// The drain timeout expired, close the remaining connections
+		for _, listener := range s.listeners {
+			listener.Close()
+		}
+		s.server.Close()
+	}
+	return err
+}
+
+// Drain stops accepting new connections and blocks until all in-flight requests
+// complete or ctx is done, then stops the background tasks and the session cleanup.
+// Unlike Stop, connections still active when ctx is done are not closed. Drain is
+// meant to be called from a SIGTERM handler for zero-downtime deployments.
+func (s *WebServer) Drain(ctx context.Context) error {
+	s.shutdownListeners(ctx)
+	err := s.server.Shutdown(ctx)
+	s.stopBackgroundTasks(ctx)
+
 	// Stop the session manager cleanup goroutine if it's the default one
 	if defaultManager, ok := s.sessionManager.(*DefaultSessionManager); ok {
 		defaultManager.Stop()
 	}
-	return s.server.Shutdown(context.Background())
+	if err != nil {
+		s.logger().Warn("libserver: server stopped before in-flight requests completed", "error", err)
+	} else {
+		s.logger().Info("libserver: server stopped")
+	}
+	return err
+}
+
+// EnableBodyBuffering buffers the request body of every handler, see BufferBody
+func (s *WebServer) EnableBodyBuffering() {
+	s.bufferBody = true
+	s.invalidateChains()
+}
+
+// SetKeepAlive enables or disables HTTP keep-alive on all the listeners and sets how long an
+// idle keep-alive connection is kept open before being closed. A zero idleTimeout falls back
+// to the read timeout of the http.Server, and keeps connections open forever without it.
+func (s *WebServer) SetKeepAlive(enabled bool, idleTimeout time.Duration) {
+	s.keepAliveOff = !enabled
+	s.idleTimeout = idleTimeout
+	s.applyKeepAlive(s.server)
+	for _, listener := range s.listeners {
+		s.applyKeepAlive(listener)
+	}
+}
+
+// applyKeepAlive applies the keep-alive settings to the given server
+func (s *WebServer) applyKeepAlive(server *http.Server) {
+	server.SetKeepAlivesEnabled(!s.keepAliveOff)
+	server.IdleTimeout = s.idleTimeout
+}
+
+// SetDefaultContentType sets the Content-Type of the responses whose handler did not set one,
+// e.g. "application/json" for APIs, instead of the type net/http sniffs from the body.
+// Responses without body (204 No Content and 304 Not Modified) are left unchanged.
+func (s *WebServer) SetDefaultContentType(ct string) {
+	s.contentType = ct
+}
+
+// SetResponseBufferSize makes responses flush to the network every time the handler has
+// written the given number of bytes, so that large streaming responses reach clients such as
+// video players sooner. net/http otherwise buffers up to 4KB and only flushes when the buffer
+// is full or the handler returns. 0 disables the periodic flush.
+func (s *WebServer) SetResponseBufferSize(bytes int) {
+	s.flushEvery = bytes
+}
+
+// SetReadHeaderTimeout sets the time allowed to read the request headers on all the
+// listeners, DefaultReadHeaderTimeout (5 seconds) by default, which protects against
+// Slowloris attacks. 0 removes the limit, which is unsafe for internet-facing servers.
+func (s *WebServer) SetReadHeaderTimeout(d time.Duration) {
+	s.server.ReadHeaderTimeout = d
+	for _, listener := range s.listeners {
+		listener.ReadHeaderTimeout = d
+	}
+}
+
+// SetDrainTimeout sets how long Stop waits for in-flight requests and background tasks
+func (s *WebServer) SetDrainTimeout(d time.Duration) {
+	s.drainTimeout = d
+}
+
+// shutdownListeners gracefully shuts down the secondary listeners
+func (s *WebServer) shutdownListeners(ctx context.Context) {
+	for _, listener := range s.listeners {
+		listener.Shutdown(ctx)
+	}
 }
 
 // wrapHandler wraps a handler function with session and server data injection
 func (s *WebServer) wrapHandler(handler func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return s.wrapGroupHandler(handler, nil)
+}
+
+// wrapGroupHandler wraps a handler function like wrapHandler, managing the sessions with the
+// session manager of the group when it has one
+func (s *WebServer) wrapGroupHandler(handler func(http.ResponseWriter, *http.Request), group *RouteGroup) http.HandlerFunc {
+	route := newRouteChain(http.HandlerFunc(handler))
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get session from cookie, if none create one
-		session := s.getOrCreateSession(w, r)
+		// Capture the response status for the stats
+		rw := newResponseWriter(w)
+		rw.flushEvery = int64(s.flushEvery)
+		rw.contentType = s.contentType
+		w = rw
+		s.stats.requestsTotal.Add(1)
+		s.stats.inFlight.Add(1)
+		start := time.Now()
+		panicked := false
+		defer func() {
+			defer s.stats.inFlight.Add(-1)
+			// A request whose handler panicked is an error, whatever status was written
+			failed := panicked || rw.statusCode >= http.StatusBadRequest
+			if failed {
+				s.stats.errorsTotal.Add(1)
+			}
+			s.stats.recordRoute(r, rw, time.Since(start), failed)
+		}()
+		if s.apmHook != nil {
+			defer s.finishAPMSpan(s.apmHook.BeforeRequest(r), rw)
+		}
+
+		// Resolve the session and the middleware with a consistent configuration, the handler
+		// runs outside the lock so that Reconfigure does not wait for in-flight requests
+		s.configMu.RLock()
 
-		// Update session last access time
-		session.Update()
+		// Answer CORS preflight requests directly
+		if s.corsConfig != nil && isPreflight(r) {
+			s.handlePreflight(w, r)
+			s.configMu.RUnlock()
+			return
+		}
+
+		// Set the CORS headers before the handler runs, so they are kept if it panics
+		if s.corsHeaders {
+			s.applyServerCORSHeaders(w, r)
+		}
+
+		// Reuse the session of a wrapped handler calling this one, so that both use the session
+		// whose cookie is sent, else get the session from the cookie, if none create one
+		scope := s.groupSessionScope(group)
+		session, ok := s.innerSession(r, scope)
+		if !ok {
+			var err error
+			session, err = s.getOrCreateSession(w, r, scope)
+			if err != nil {
+				s.configMu.RUnlock()
+				s.logger().ErrorContext(r.Context(), "libserver: cannot create session", "error", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			// Update session last access time
+			session.Update()
+			if s.auditAccess {
+				s.auditSession(SessionEventAccessed, session.Id(), r)
+			}
+		}
 
 		// Inject server data and session into context
 		ctx := context.WithValue(r.Context(), ServerDataKey, s.data)
-		ctx = context.WithValue(ctx, ContextKey(s.applicationName), session)
+		ctx = context.WithValue(ctx, sessionContextKey{}, session)
+		ctx = context.WithValue(ctx, sessionScopeContextKey{}, scope)
 
-		handler(w, r.WithContext(ctx))
+		chain := route.get(s)
+		s.configMu.RUnlock()
+
+		r = r.WithContext(ctx)
+		panicked = true
+		s.runRequestHooks(r)
+		chain.ServeHTTP(w, r)
+		s.runResponseHooks(r, rw)
+		panicked = false
 	}
 }
 
-// getOrCreateSession retrieves or creates a session for the request
-func (s *WebServer) getOrCreateSession(w http.ResponseWriter, r *http.Request) Session {
-	sessionCookie, err := r.Cookie(s.applicationName)
-	if err == nil {
-		// Cookie exists, try to get the session
-		if session := s.sessionManager.GetSession(sessionCookie.Value); session != nil && !session.IsExpired() {
-			return session
+// innerSession returns the session already injected into the request by a wrapped handler
+// calling another one, if it was resolved with the same session scope
+func (s *WebServer) innerSession(r *http.Request, scope sessionScope) (Session, bool) {
+	session, ok := LookupSession(r.Context())
+	if !ok {
+		return nil, false
+	}
+	outer, ok := r.Context().Value(sessionScopeContextKey{}).(sessionScope)
+	if !ok || outer.manager != scope.manager || outer.cookiePath != scope.cookiePath || outer.cookieSuffix != scope.cookieSuffix {
+		return nil, false
+	}
+	return session, true
+}
+
+// applyMiddleware wraps the handler with the global middleware, the first registered being
+// the outermost, and with the body buffering and the concurrency limit if enabled
+func (s *WebServer) applyMiddleware(handler http.Handler) http.Handler {
+	if s.debug {
+		handler = captureDebugContext(handler)
+	}
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		handler = s.middleware[i](handler)
+	}
+	if s.bufferBody {
+		handler = BufferBody(handler)
+	}
+	if s.semaphore != nil {
+		handler = s.limitConcurrency(s.semaphore, handler)
+	}
+	if s.debug {
+		handler = s.debugHandler(handler)
+	}
+	return handler
+}
+
+// Use adds global middleware applied to every handler, after the session injection.
+// Middleware run in registration order: the first one registered is the outermost.
+// Each middleware wraps each route once, on its first request, and again only when the
+// middleware change, so state created when wrapping persists across requests.
+// It returns the server for chaining, e.g. server.Use(logging).Use(recovery).
+func (s *WebServer) Use(middleware ...func(http.Handler) http.Handler) *WebServer {
+	for _, mw := range middleware {
+		s.UseNamed(funcName(mw), mw)
+	}
+	return s
+}
+
+// UseNamed adds a global middleware like Use, under a name reported by Middleware
+func (s *WebServer) UseNamed(name string, mw func(http.Handler) http.Handler) *WebServer {
+	s.middleware = append(s.middleware, mw)
+	s.middlewareLabels = append(s.middlewareLabels, name)
+	s.invalidateChains()
+	return s
+}
+
+// Middleware returns the names of the global middleware in registration order: the name given
+// to UseNamed, or the function name derived by Use (e.g. "main.logging" or, for middleware
+// returned by a constructor, "github.com/Morditux/libserver.CORSMiddleware.func1")
+func (s *WebServer) Middleware() []string {
+	return slices.Clone(s.middlewareLabels)
+}
+
+// getOrCreateSession retrieves or creates a session for the request, returning an error if
+// the new session could not be initialized
+func (s *WebServer) getOrCreateSession(w http.ResponseWriter, r *http.Request, scope sessionScope) (Session, error) {
+	tenant := s.resolveTenant(r)
+	cookieName := scope.cookieName(s, tenant)
+
+	// Try the session cookies first, as the cookie of a route group session manager is sent
+	// along with the server one, then the session token header
+	var ids []string
+	for _, sessionCookie := range r.CookiesNamed(cookieName) {
+		ids = append(ids, sessionCookie.Value)
+	}
+	if token := s.sessionToken(r); token != "" {
+		ids = append(ids, token)
+	}
+	for _, id := range ids {
+		if s.idValidator != nil && !s.idValidator(id) {
+			continue
+		}
+		session := scope.manager.GetSession(id)
+		if session == nil || !belongsToTenant(session, tenant) {
+			continue
+		}
+		if session.IsExpired() {
+			scope.manager.DeleteSession(id)
+			s.auditSession(SessionEventExpired, id, r)
+			continue
 		}
+		if !s.checkSessionIP(session, r) {
+			scope.manager.DeleteSession(id)
+			s.auditSession(SessionEventIPChanged, id, r)
+			continue
+		}
+		s.renewSessionCookie(w, scope.sessionCookie(s, cookieName, session.Id()), session)
+		s.writeSessionToken(w, r, session)
+		return session, nil
 	}
 
 	// Create a new session
-	session := s.sessionManager.CreateSession()
-	http.SetCookie(w, &http.Cookie{
-		Name:     s.applicationName,
-		Value:    session.Id(),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   s.withHttps,
-		SameSite: http.SameSiteLaxMode,
-	})
-	return session
+	session, err := s.createTenantSession(scope.manager, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if !s.checkSessionIP(session, r) {
+		scope.manager.DeleteSession(session.Id())
+		return nil, errSessionIP
+	}
+	s.auditSession(SessionEventCreated, session.Id(), r)
+	setSessionCookie(w, scope.sessionCookie(s, cookieName, session.Id()))
+	s.writeSessionToken(w, r, session)
+	return session, nil
+}
+
+// DestroySession deletes the session of the request and expires its cookie, e.g. on logout
+func (s *WebServer) DestroySession(w http.ResponseWriter, r *http.Request) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	session := GetSession(r.Context())
+	if session == nil {
+		return
+	}
+	scope := s.requestSessionScope(r)
+	scope.manager.DeleteSession(session.Id())
+	s.auditSession(SessionEventDeleted, session.Id(), r)
+	cookie := scope.sessionCookie(s, scope.cookieName(s, s.resolveTenant(r)), "")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
 }
 
-// AddHandlerFunc adds a handler function for the given pattern
+// AddHandlerFunc adds a handler function for the given pattern.
+// It panics if the server uses a custom router, see WithRouter.
 func (s *WebServer) AddHandlerFunc(pattern string, handler http.HandlerFunc) {
-	s.mux.HandleFunc(pattern, s.wrapHandler(handler))
+	s.AddHandler(pattern, handler)
 }
 
-// AddHandler adds a handler for the given pattern
+// AddHandler adds a handler for the given pattern. For method-based patterns such as
+// "POST /api/items", OPTIONS requests for the path are answered with the allowed methods
+// and get a session, unless an OPTIONS handler is registered for it.
+// It panics if the server uses a custom router, see WithRouter.
 func (s *WebServer) AddHandler(pattern string, handler http.Handler) {
+	s.checkNoRouter()
+	s.routes = append(s.routes, pattern)
+	s.logRoute(pattern)
+	if s.setOptionsHandler(pattern, handler) {
+		return
+	}
 	s.mux.HandleFunc(pattern, s.wrapHandler(handler.ServeHTTP))
+	s.addOptionsRoute(pattern)
+}
+
+// AddHandlerFuncWithMiddleware adds a handler function for the given pattern wrapped with
+// route-specific middleware. The first middleware is the innermost, closest to the handler;
+// the global middleware registered with Use wrap the result.
+func (s *WebServer) AddHandlerFuncWithMiddleware(pattern string, handler http.HandlerFunc, middleware ...func(http.Handler) http.Handler) {
+	var wrapped http.Handler = handler
+	for _, mw := range middleware {
+		wrapped = mw(wrapped)
+	}
+	s.AddHandler(pattern, wrapped)
+}
+
+// checkNoRouter panics if routes are registered on a server using a custom router
+func (s *WebServer) checkNoRouter() {
+	if s.router != nil {
+		panic("libserver: " + ErrCustomRouter.Error())
+	}
+}
+
+// AddHandlerFuncWithStrip adds a handler function for the given pattern, removing
+// stripPrefix from the request path before calling it. This allows mounting
+// sub-applications or file servers under a prefix without the prefix leaking into
+// their routing; requests whose path does not start with stripPrefix get a 404.
+func (s *WebServer) AddHandlerFuncWithStrip(pattern, stripPrefix string, handler http.HandlerFunc) {
+	s.AddHandler(pattern, http.StripPrefix(stripPrefix, handler))
 }
 
 // GetServerData returns the server's shared data store
@@ -152,22 +694,73 @@ func (s *WebServer) GetApplicationName() string {
 	return s.applicationName
 }
 
+// SetSessionCookieName overrides the session cookie name, which defaults to the application name.
+func (s *WebServer) SetSessionCookieName(name string) {
+	s.cookieName = name
+}
+
+// GetSessionCookieName returns the name of the session cookie
+func (s *WebServer) GetSessionCookieName() string {
+	if s.cookieName != "" {
+		return s.cookieName
+	}
+	return s.applicationName
+}
+
 // GetAddress returns the server address
 func (s *WebServer) GetAddress() string {
 	return s.address
 }
 
+// SetAddress sets the address the server listens on. It must be called before Start,
+// ErrServerStarted is returned otherwise.
+func (s *WebServer) SetAddress(address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrServerStarted
+	}
+	s.address = address
+	s.server.Addr = fmt.Sprintf("%s:%d", s.address, s.port)
+	return nil
+}
+
+// SetPort sets the port the server listens on. It must be called before Start,
+// ErrServerStarted is returned otherwise.
+func (s *WebServer) SetPort(port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrServerStarted
+	}
+	s.port = port
+	s.server.Addr = fmt.Sprintf("%s:%d", s.address, s.port)
+	return nil
+}
+
 // GetPort returns the server port
 func (s *WebServer) GetPort() int {
 	return s.port
 }
 
-// GetSessionFromContext retrieves the session from a request context
+// GetSession retrieves the session from a request context
+func GetSession(ctx context.Context) Session {
+	session, _ := LookupSession(ctx)
+	return session
+}
+
+// LookupSession retrieves the session from a request context and reports whether there is one
+func LookupSession(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(Session)
+	return session, ok
+}
+
+// GetSessionFromContext retrieves the session from a request context. The application name
+// is ignored, since the session is no longer stored under it, and kept for compatibility.
+//
+// Deprecated: use GetSession.
 func GetSessionFromContext(ctx context.Context, appName string) Session {
-	if session, ok := ctx.Value(ContextKey(appName)).(Session); ok {
-		return session
-	}
-	return nil
+	return GetSession(ctx)
 }
 
 // GetServerDataFromContext retrieves the ServerData from a request context
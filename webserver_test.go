@@ -0,0 +1,35 @@
+package libserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// TestUseChaining checks that middleware added with chained Use calls all run, the first
+// one registered being the outermost
+func TestUseChaining(t *testing.T) {
+	s := newTestServer(t)
+	var calls []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	s.Use(record("a")).Use(record("b")).AddHandlerFunc("GET /x", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if want := []string{"a", "b", "handler"}; !slices.Equal(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	if got := len(s.Middleware()); got != 2 {
+		t.Errorf("len(Middleware()) = %d, want 2", got)
+	}
+}
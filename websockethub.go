@@ -0,0 +1,220 @@
+package libserver
+
+import "sync"
+
+// WebSocketConn is a WebSocket connection managed by a WebSocketHub.
+// Implementations must be comparable (typically a pointer type) since
+// connections are used as map keys.
+type WebSocketConn interface {
+	WriteMessage(msg []byte) error
+}
+
+// WebSocketSendQueueSize is the number of messages queued for a connection before the hub
+// considers it too slow and unregisters it
+const WebSocketSendQueueSize = 64
+
+// hubClient is a registered connection, with its rooms and the queue drained by its writer
+type hubClient struct {
+	rooms map[string]struct{}
+	queue chan []byte
+}
+
+// roomMembership is a request to add or remove a connection from a room
+type roomMembership struct {
+	conn WebSocketConn
+	room string
+}
+
+// roomMessage is a message to broadcast to the members of a room
+type roomMessage struct {
+	room string
+	msg  []byte
+}
+
+// WebSocketHub manages WebSocket connections with broadcast and room support.
+// All mutations are serialized through channels to a single goroutine, so no
+// lock is held while messages fan out to the connections. Each connection is written
+// by its own goroutine from a queue of WebSocketSendQueueSize messages, so a slow client
+// does not delay the others: a connection whose queue is full or whose write fails is
+// unregistered, and its pending messages are dropped.
+type WebSocketHub struct {
+	conns           map[WebSocketConn]*hubClient
+	rooms           map[string]map[WebSocketConn]struct{}
+	registerCh      chan WebSocketConn
+	unregisterCh    chan WebSocketConn
+	broadcastCh     chan []byte
+	joinCh          chan roomMembership
+	leaveCh         chan roomMembership
+	roomBroadcastCh chan roomMessage
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+}
+
+// NewWebSocketHub creates a new hub and starts its goroutine
+func NewWebSocketHub() *WebSocketHub {
+	hub := &WebSocketHub{
+		conns:           make(map[WebSocketConn]*hubClient),
+		rooms:           make(map[string]map[WebSocketConn]struct{}),
+		registerCh:      make(chan WebSocketConn),
+		unregisterCh:    make(chan WebSocketConn),
+		broadcastCh:     make(chan []byte),
+		joinCh:          make(chan roomMembership),
+		leaveCh:         make(chan roomMembership),
+		roomBroadcastCh: make(chan roomMessage),
+		stopCh:          make(chan struct{}),
+	}
+	go hub.run()
+	return hub
+}
+
+// run processes the hub operations until the hub is stopped
+func (h *WebSocketHub) run() {
+	for {
+		select {
+		case conn := <-h.registerCh:
+			h.register(conn)
+		case conn := <-h.unregisterCh:
+			h.unregister(conn)
+		case msg := <-h.broadcastCh:
+			for conn := range h.conns {
+				h.send(conn, msg)
+			}
+		case m := <-h.joinCh:
+			h.register(m.conn)
+			h.conns[m.conn].rooms[m.room] = struct{}{}
+			if h.rooms[m.room] == nil {
+				h.rooms[m.room] = make(map[WebSocketConn]struct{})
+			}
+			h.rooms[m.room][m.conn] = struct{}{}
+		case m := <-h.leaveCh:
+			h.leave(m.conn, m.room)
+		case m := <-h.roomBroadcastCh:
+			for conn := range h.rooms[m.room] {
+				h.send(conn, m.msg)
+			}
+		case <-h.stopCh:
+			// Stop the writers
+			for conn := range h.conns {
+				h.unregister(conn)
+			}
+			return
+		}
+	}
+}
+
+// register adds a connection to the hub and starts its writer if it is not already registered
+func (h *WebSocketHub) register(conn WebSocketConn) {
+	if _, ok := h.conns[conn]; ok {
+		return
+	}
+	client := &hubClient{
+		rooms: make(map[string]struct{}),
+		queue: make(chan []byte, WebSocketSendQueueSize),
+	}
+	h.conns[conn] = client
+	go h.write(conn, client.queue)
+}
+
+// write writes the queued messages to a connection until its queue is closed, unregistering
+// the connection if a write fails
+func (h *WebSocketHub) write(conn WebSocketConn, queue <-chan []byte) {
+	for msg := range queue {
+		if err := conn.WriteMessage(msg); err != nil {
+			h.Unregister(conn)
+			// Drain the queue until the hub closes it
+			for range queue {
+			}
+			return
+		}
+	}
+}
+
+// unregister removes a connection from the hub and from all its rooms, and stops its writer
+func (h *WebSocketHub) unregister(conn WebSocketConn) {
+	client, ok := h.conns[conn]
+	if !ok {
+		return
+	}
+	for room := range client.rooms {
+		h.leave(conn, room)
+	}
+	delete(h.conns, conn)
+	close(client.queue)
+}
+
+// leave removes a connection from a room, deleting the room when it becomes empty
+func (h *WebSocketHub) leave(conn WebSocketConn, room string) {
+	if client, ok := h.conns[conn]; ok {
+		delete(client.rooms, room)
+	}
+	if members, ok := h.rooms[room]; ok {
+		delete(members, conn)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// send queues a message for a connection, unregistering the connection if its queue is full
+func (h *WebSocketHub) send(conn WebSocketConn, msg []byte) {
+	select {
+	case h.conns[conn].queue <- msg:
+	default:
+		h.unregister(conn)
+	}
+}
+
+// Register adds a connection to the hub
+func (h *WebSocketHub) Register(conn WebSocketConn) {
+	select {
+	case h.registerCh <- conn:
+	case <-h.stopCh:
+	}
+}
+
+// Unregister removes a connection from the hub and from all its rooms
+func (h *WebSocketHub) Unregister(conn WebSocketConn) {
+	select {
+	case h.unregisterCh <- conn:
+	case <-h.stopCh:
+	}
+}
+
+// Broadcast sends a message to all registered connections
+func (h *WebSocketHub) Broadcast(msg []byte) {
+	select {
+	case h.broadcastCh <- msg:
+	case <-h.stopCh:
+	}
+}
+
+// JoinRoom adds a connection to a room, registering the connection if needed
+func (h *WebSocketHub) JoinRoom(conn WebSocketConn, room string) {
+	select {
+	case h.joinCh <- roomMembership{conn: conn, room: room}:
+	case <-h.stopCh:
+	}
+}
+
+// LeaveRoom removes a connection from a room
+func (h *WebSocketHub) LeaveRoom(conn WebSocketConn, room string) {
+	select {
+	case h.leaveCh <- roomMembership{conn: conn, room: room}:
+	case <-h.stopCh:
+	}
+}
+
+// BroadcastRoom sends a message to all connections in a room
+func (h *WebSocketHub) BroadcastRoom(room string, msg []byte) {
+	select {
+	case h.roomBroadcastCh <- roomMessage{room: room, msg: msg}:
+	case <-h.stopCh:
+	}
+}
+
+// Stop stops the hub goroutine, further operations are ignored
+func (h *WebSocketHub) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+}
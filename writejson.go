@@ -0,0 +1,19 @@
+package libserver
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WriteJSON writes v as a JSON response with the given status code. v is encoded before
+// anything is written, so an encoding error does not send a partial response.
+func WriteJSON(w http.ResponseWriter, statusCode int, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}